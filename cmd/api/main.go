@@ -1,11 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"api-backend/internal/auth"
 	"api-backend/internal/database"
 	"api-backend/internal/handlers"
+	"api-backend/internal/logging"
+	"api-backend/internal/metrics"
 	"api-backend/internal/middleware"
+	"api-backend/internal/presence"
+	"api-backend/internal/ratelimit"
+	"api-backend/internal/realtime"
+	"api-backend/internal/repository/postgres"
+	"api-backend/internal/spoofing"
+	"api-backend/internal/tracing"
 	"api-backend/pkg/config"
 
 	"github.com/gin-gonic/gin"
@@ -17,14 +35,24 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	db, err := database.New(cfg.DatabaseURL)
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel))
+
+	// Cloud Run sends SIGTERM before stopping an instance
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesEndpoint, cfg.ServiceName, cfg.TraceSampleRatio)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+
+	db, err := database.New(cfg.DatabaseURL, cfg.QueryTimeout)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
 	}
-	defer db.Close()
 
 	if err := db.RunMigrations(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		fatal("failed to run migrations", err)
 	}
 
 	if cfg.Environment == "production" {
@@ -32,35 +60,180 @@ func main() {
 	}
 
 	router := gin.New()
-	router.Use(gin.Recovery())
+	// Without trusted proxies the client IP is the connection's address, so
+	// X-Forwarded-For cannot pick a fresh rate limit bucket per request
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("invalid TRUSTED_PROXIES", err)
+	}
+	router.Use(middleware.Tracing())
+	router.Use(middleware.RequestContext(cfg.GoogleCloudProject))
 	router.Use(middleware.Logger())
+	router.Use(middleware.Metrics())
+	router.Use(gin.Recovery())
 	router.Use(middleware.CORS(cfg.AllowedOrigins))
+	router.Use(middleware.Admins(cfg.AdminUserIDs))
+
+	tokenManager := auth.NewTokenManager(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	requireAuth := middleware.Auth(tokenManager)
+
+	var limitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		limitStore = ratelimit.NewPostgresStore(db)
+	}
+	// limitAPI runs after requireAuth so it limits per user; the tighter
+	// limits on signup and login can only go by client IP
+	limitAPI := middleware.RateLimit(limitStore, ratelimit.Policy{Name: "api", Limit: 300, Period: time.Minute})
+	limitAuth := middleware.RateLimit(limitStore, ratelimit.Policy{Name: "auth", Limit: 10, Period: time.Minute})
+	limitSignup := middleware.RateLimit(limitStore, ratelimit.Policy{Name: "signup", Limit: 5, Period: time.Hour})
+	limitLocation := middleware.RateLimit(limitStore, ratelimit.Policy{Name: "location", Limit: 10, Period: 10 * time.Second})
+	limitBatch := middleware.RateLimit(limitStore, ratelimit.Policy{Name: "location-batch", Limit: 10, Period: time.Minute})
+
+	userRepo := postgres.NewUserRepository(db)
+	radarRepo := postgres.NewRadarRepository(db, cfg.PresenceTTL)
+	geofenceRepo := postgres.NewGeofenceRepository(db)
+	flagRepo := postgres.NewLocationFlagRepository(db)
+	radarHub := realtime.NewMemoryHub(64)
+
+	metrics.RegisterDB(db.DB, "api")
+	metrics.RegisterRadar(radarRepo)
+	metricsRoutes := router.Group("/metrics")
+	if cfg.MetricsToken != "" {
+		metricsRoutes.Use(middleware.StaticToken(cfg.MetricsToken))
+	}
+	metricsRoutes.GET("", gin.WrapH(metrics.Handler()))
+
+	// Background workers run until shutdown cancels workerCtx
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	janitor := presence.NewJanitor(radarRepo, radarHub, cfg.JanitorInterval)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		janitor.Run(workerCtx)
+	}()
 
 	healthHandler := handlers.NewHealthHandler(db)
-	userHandler := handlers.NewUserHandler(db)
-	radarHandler := handlers.NewRadarHandler(db)
+	authHandler := handlers.NewAuthHandler(userRepo, tokenManager)
+	userHandler := handlers.NewUserHandler(userRepo)
+	spoofChecker := spoofing.NewChecker(cfg.MaxSpeedKmh, cfg.SpoofMode)
+	radarHandler := handlers.NewRadarHandler(userRepo, radarRepo, geofenceRepo, flagRepo, radarHub, spoofChecker)
+	geofenceHandler := handlers.NewGeofenceHandler(geofenceRepo)
+	blockHandler := handlers.NewBlockHandler(userRepo, radarRepo, radarHub)
+	moderationHandler := handlers.NewModerationHandler(flagRepo)
 
 	api := router.Group("/api/v1")
 	{
-		api.GET("/health", healthHandler.Check)
+		health := api.Group("/health")
+		{
+			// GET /health predates the split and stays as the readiness probe
+			health.GET("", healthHandler.Ready)
+			health.GET("/live", healthHandler.Live)
+			health.GET("/ready", healthHandler.Ready)
+		}
+
+		authRoutes := api.Group("/auth", limitAuth)
+		{
+			authRoutes.POST("/login", authHandler.Login)
+			authRoutes.POST("/refresh", authHandler.Refresh)
+		}
 
 		users := api.Group("/users")
 		{
-			users.POST("", userHandler.Create)
-			users.GET("", userHandler.List)
-			users.GET("/:id", userHandler.GetByID)
-			users.DELETE("/:id", userHandler.Delete)
+			users.POST("", limitSignup, userHandler.Create)
+
+			authed := users.Group("", requireAuth, limitAPI)
+			authed.GET("", userHandler.List)
+			authed.GET("/:id", userHandler.GetByID)
+			authed.PATCH("/:id", userHandler.Update)
+			authed.DELETE("/:id", userHandler.Delete)
+			authed.GET("/:id/profile", userHandler.GetProfile)
+			authed.PUT("/:id/profile", userHandler.UpdateProfile)
+			authed.POST("/:id/blocks", blockHandler.Block)
+			authed.DELETE("/:id/blocks", blockHandler.Unblock)
+			authed.GET("/:id/geofence-events", geofenceHandler.ListUserEvents)
 		}
 
-		radar := api.Group("/radar")
+		radar := api.Group("/radar", requireAuth, limitAPI)
 		{
-			radar.POST("/location", radarHandler.UpdateLocation)
+			radar.POST("/location", limitLocation, radarHandler.UpdateLocation)
+			radar.POST("/location/batch", limitBatch, radarHandler.BatchUpdateLocation)
 			radar.GET("/nearby", radarHandler.GetNearbyUsers)
+			radar.GET("/bbox", radarHandler.GetUsersInBoundingBox)
+			radar.POST("/polygon", radarHandler.FindUsersInPolygon)
+			radar.GET("/clusters", radarHandler.GetClusters)
+			radar.GET("/stream", radarHandler.StreamNearbyUsers)
+			radar.GET("/users/:id/history", radarHandler.GetLocationHistory)
+			radar.GET("/privacy", radarHandler.GetPrivacy)
+			radar.PUT("/privacy", radarHandler.UpdatePrivacy)
+		}
+
+		geofences := api.Group("/geofences", requireAuth, limitAPI)
+		{
+			geofences.POST("", geofenceHandler.Create)
+			geofences.GET("", geofenceHandler.List)
+			geofences.GET("/:id", geofenceHandler.GetByID)
+			geofences.PUT("/:id", geofenceHandler.Update)
+			geofences.DELETE("/:id", geofenceHandler.Delete)
+			geofences.GET("/:id/events", geofenceHandler.ListEvents)
+		}
+
+		admin := api.Group("/admin", requireAuth, middleware.RequireAdmin(), limitAPI)
+		{
+			admin.GET("/users/:id/location-flags", moderationHandler.ListLocationFlags)
 		}
 	}
 
-	log.Printf("Server starting on port %s in %s mode", cfg.Port, cfg.Environment)
-	if err := router.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
 	}
+	// Shutdown waits for active connections, so end the radar streams
+	server.RegisterOnShutdown(radarHub.Close)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "port", cfg.Port, "environment", cfg.Environment)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		fatal("failed to start server", err)
+	case <-ctx.Done():
+	}
+	// A second signal kills the process without waiting
+	stop()
+
+	// Fail readiness first and keep serving for ShutdownDelay, so load
+	// balancers that probe readiness stop sending new requests
+	slog.Info("shutting down", "delay", cfg.ShutdownDelay, "timeout", cfg.ShutdownTimeout)
+	healthHandler.Drain()
+	time.Sleep(cfg.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Stop taking requests and drain in-flight ones, then stop the workers
+	// that still write to the database, then flush spans and close the pool
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("failed to drain connections", "error", err)
+	}
+	stopWorkers()
+	workers.Wait()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	if err := db.Close(); err != nil {
+		slog.Error("failed to close database", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type claims struct {
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair is returned to clients after a successful login or refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// TokenManager issues and verifies HMAC-SHA256 signed JWTs
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewTokenManager(secret string, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+// IssuePair creates a fresh access/refresh token pair for the given user
+func (m *TokenManager) IssuePair(userID int) (*TokenPair, error) {
+	access, err := m.sign(userID, tokenTypeAccess, m.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := m.sign(userID, tokenTypeRefresh, m.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(m.accessTTL.Seconds()),
+	}, nil
+}
+
+// ParseAccessToken validates an access token and returns its subject
+func (m *TokenManager) ParseAccessToken(token string) (int, error) {
+	return m.parse(token, tokenTypeAccess)
+}
+
+// ParseRefreshToken validates a refresh token and returns its subject
+func (m *TokenManager) ParseRefreshToken(token string) (int, error) {
+	return m.parse(token, tokenTypeRefresh)
+}
+
+func (m *TokenManager) sign(userID int, tokenType string, ttl time.Duration) (string, error) {
+	now := m.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+
+	signed, err := token.SignedString(m.secret)
+	if err != nil {
+		return "", fmt.Errorf("error signing token: %w", err)
+	}
+	return signed, nil
+}
+
+func (m *TokenManager) parse(token, tokenType string) (int, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(m.now),
+	)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	if c.TokenType != tokenType {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	return userID, nil
+}
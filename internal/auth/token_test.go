@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenManager_IssueAndParse(t *testing.T) {
+	m := NewTokenManager("secret", time.Minute, time.Hour)
+
+	pair, err := m.IssuePair(42)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.Equal(t, 60, pair.ExpiresIn)
+
+	userID, err := m.ParseAccessToken(pair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, userID)
+
+	userID, err = m.ParseRefreshToken(pair.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, userID)
+}
+
+func TestTokenManager_RejectsWrongTokenType(t *testing.T) {
+	m := NewTokenManager("secret", time.Minute, time.Hour)
+
+	pair, err := m.IssuePair(1)
+	assert.NoError(t, err)
+
+	_, err = m.ParseAccessToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = m.ParseRefreshToken(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestTokenManager_RejectsWrongSecret(t *testing.T) {
+	issuer := NewTokenManager("secret", time.Minute, time.Hour)
+	verifier := NewTokenManager("other-secret", time.Minute, time.Hour)
+
+	pair, err := issuer.IssuePair(1)
+	assert.NoError(t, err)
+
+	_, err = verifier.ParseAccessToken(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestTokenManager_RejectsExpired(t *testing.T) {
+	m := NewTokenManager("secret", time.Minute, time.Hour)
+	issuedAt := time.Now()
+	m.now = func() time.Time { return issuedAt }
+
+	pair, err := m.IssuePair(1)
+	assert.NoError(t, err)
+
+	m.now = func() time.Time { return issuedAt.Add(2 * time.Minute) }
+
+	_, err = m.ParseAccessToken(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = m.ParseRefreshToken(pair.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestPassword_HashAndCheck(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "correct horse", hash)
+
+	assert.True(t, CheckPassword(hash, "correct horse"))
+	assert.False(t, CheckPassword(hash, "battery staple"))
+}
@@ -1,20 +1,49 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/XSAM/otelsql"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
+// queryCanceled is the Postgres error code for a statement stopped by
+// statement_timeout or by a cancel request, which lib/pq sends when a
+// query's context ends
+const queryCanceled = "57014"
+
 type Database struct {
-	DB *sql.DB
+	DB           *sql.DB
+	queryTimeout time.Duration
 }
 
-func New(databaseURL string) (*Database, error) {
-	db, err := sql.Open("postgres", databaseURL)
+// New connects to databaseURL. Every query run with a context gets a span as
+// a child of the context's span; query arguments are not recorded. A
+// positive queryTimeout is both the deadline WithTimeout applies and the
+// pool's Postgres statement_timeout, which stops statements server-side even
+// if the client's cancel request is lost.
+func New(databaseURL string, queryTimeout time.Duration) (*Database, error) {
+	dsn, err := withStatementTimeout(databaseURL, queryTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := otelsql.Open("postgres", dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+		}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %w", err)
 	}
@@ -29,13 +58,52 @@ func New(databaseURL string) (*Database, error) {
 
 	log.Println("Successfully connected to database")
 
-	return &Database{DB: db}, nil
+	return &Database{DB: db, queryTimeout: queryTimeout}, nil
 }
 
 func (d *Database) Close() error {
 	return d.DB.Close()
 }
 
-func (d *Database) Health() error {
-	return d.DB.Ping()
+// WithTimeout derives the context for one repository call, bounded by the
+// query timeout on top of any deadline ctx already has
+func (d *Database) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.queryTimeout)
+}
+
+// IsTimeout reports whether err means a query ran out of time, either on its
+// context's deadline or on statement_timeout. A query cancelled because its
+// request ended fails the same way, so callers check the request first.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == queryCanceled
+}
+
+// withStatementTimeout adds statement_timeout to a postgres:// URL or a
+// key=value connection string, unless it sets one already. lib/pq passes
+// parameters it does not know to the server as session settings.
+func withStatementTimeout(dsn string, timeout time.Duration) (string, error) {
+	if timeout <= 0 || strings.Contains(dsn, "statement_timeout") {
+		return dsn, nil
+	}
+	ms := strconv.FormatInt(timeout.Milliseconds(), 10)
+
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " statement_timeout=" + ms, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("error parsing database URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("statement_timeout", ms)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
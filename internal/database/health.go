@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CheckError is a problem found by a readiness check, with a message that
+// is safe to show to callers. Other errors from the checks come from
+// running them, such as a lost connection.
+type CheckError struct {
+	Message string
+}
+
+func (e *CheckError) Error() string {
+	return e.Message
+}
+
+// Ping checks that a connection to the database can be used
+func (d *Database) Ping(ctx context.Context) error {
+	return d.DB.PingContext(ctx)
+}
+
+// CheckPostGIS checks that the postgis extension is installed
+func (d *Database) CheckPostGIS(ctx context.Context) error {
+	var installed bool
+	err := d.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'postgis')").Scan(&installed)
+	if err != nil {
+		return err
+	}
+	if !installed {
+		return &CheckError{Message: "postgis extension is not installed"}
+	}
+	return nil
+}
+
+// CheckMigrations checks that every migration embedded in this binary has
+// been applied, not just the latest one, so a migration skipped or rolled
+// back in the middle is caught too. A database that is further along, as
+// during a rolling deploy, still passes.
+func (d *Database) CheckMigrations(ctx context.Context) error {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return err
+	}
+
+	rows, err := d.DB.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if missing := missingMigrations(migrations, applied); len(missing) > 0 {
+		versions := make([]string, len(missing))
+		for i, version := range missing {
+			versions[i] = strconv.Itoa(version)
+		}
+		return &CheckError{Message: "migrations not applied: " + strings.Join(versions, ", ")}
+	}
+	return nil
+}
+
+// missingMigrations returns the versions of the migrations not in applied, in
+// order
+func missingMigrations(migrations []Migration, applied map[int]bool) []int {
+	var missing []int
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			missing = append(missing, m.Version)
+		}
+	}
+	return missing
+}
+
+// CheckPool checks that the connection pool has a connection to spare
+func (d *Database) CheckPool(ctx context.Context) error {
+	stats := d.DB.Stats()
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+		return &CheckError{Message: fmt.Sprintf("all %d connections are in use", stats.MaxOpenConnections)}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessChecks(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("DATABASE_URL not set, skipping Postgres integration test")
+	}
+
+	db, err := New(databaseURL, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.RunMigrations())
+
+	ctx := context.Background()
+	assert.NoError(t, db.Ping(ctx))
+	assert.NoError(t, db.CheckPostGIS(ctx))
+	assert.NoError(t, db.CheckMigrations(ctx))
+	assert.NoError(t, db.CheckPool(ctx))
+}
+
+func TestMissingMigrations(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+
+	assert.Empty(t, missingMigrations(migrations, map[int]bool{1: true, 2: true, 3: true}))
+	// A newer database passes, but a gap in the middle does not
+	assert.Empty(t, missingMigrations(migrations, map[int]bool{1: true, 2: true, 3: true, 4: true}))
+	assert.Equal(t, []int{2}, missingMigrations(migrations, map[int]bool{1: true, 3: true}))
+	assert.Equal(t, []int{1, 2, 3}, missingMigrations(migrations, map[int]bool{}))
+}
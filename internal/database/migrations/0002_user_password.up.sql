@@ -0,0 +1,2 @@
+-- Password hash for JWT login (nullable for accounts created before auth)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255);
@@ -0,0 +1,10 @@
+-- Append-only trail of every location update; user_radar only keeps the latest
+CREATE TABLE IF NOT EXISTS location_history (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	location GEOGRAPHY(POINT, 4326) NOT NULL,
+	is_active BOOLEAN NOT NULL,
+	recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_history_user_recorded_at ON location_history(user_id, recorded_at DESC);
@@ -0,0 +1,30 @@
+-- Personal geofences: evaluated only against their owner's location updates
+CREATE TABLE IF NOT EXISTS geofences (
+	id SERIAL PRIMARY KEY,
+	owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	fence_type VARCHAR(16) NOT NULL CHECK (fence_type IN ('circle', 'polygon')),
+	center GEOGRAPHY(POINT, 4326),
+	radius_meters DOUBLE PRECISION,
+	area GEOGRAPHY(POLYGON, 4326),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK (
+		(fence_type = 'circle' AND center IS NOT NULL AND radius_meters > 0 AND area IS NULL) OR
+		(fence_type = 'polygon' AND area IS NOT NULL AND center IS NULL AND radius_meters IS NULL)
+	)
+);
+
+CREATE INDEX IF NOT EXISTS idx_geofences_owner_id ON geofences(owner_id);
+
+CREATE TABLE IF NOT EXISTS geofence_events (
+	id BIGSERIAL PRIMARY KEY,
+	geofence_id INTEGER NOT NULL REFERENCES geofences(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	event_type VARCHAR(8) NOT NULL CHECK (event_type IN ('enter', 'exit')),
+	location GEOGRAPHY(POINT, 4326) NOT NULL,
+	occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_events_user_occurred_at ON geofence_events(user_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_geofence_occurred_at ON geofence_events(geofence_id, occurred_at DESC);
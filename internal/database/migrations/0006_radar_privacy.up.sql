@@ -0,0 +1,8 @@
+-- Per-user radar visibility; users without a row are shown exactly
+CREATE TABLE IF NOT EXISTS user_radar_privacy (
+	user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	visibility VARCHAR(20) NOT NULL DEFAULT 'exact'
+		CHECK (visibility IN ('exact', 'fuzzed', 'distance_only', 'hidden')),
+	fuzz_meters INTEGER NOT NULL DEFAULT 0 CHECK (fuzz_meters >= 0),
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
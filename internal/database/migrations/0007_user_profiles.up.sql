@@ -0,0 +1,5 @@
+-- Public profile shown to other users instead of the email address
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS display_name VARCHAR(50) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS bio VARCHAR(500) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_user_radar_active_updated_at;
@@ -0,0 +1,2 @@
+-- Supports the presence janitor and the staleness filter on nearby queries
+CREATE INDEX IF NOT EXISTS idx_user_radar_active_updated_at ON user_radar(updated_at) WHERE is_active = true;
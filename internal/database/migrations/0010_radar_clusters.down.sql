@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS radar_fuzz(geometry, integer);
+DROP INDEX IF EXISTS idx_user_radar_location_geometry;
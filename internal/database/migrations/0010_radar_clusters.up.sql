@@ -0,0 +1,21 @@
+-- Cluster queries filter on planar bounding boxes, which the geography index
+-- on location cannot answer
+CREATE INDEX IF NOT EXISTS idx_user_radar_location_geometry ON user_radar USING GIST ((location::geometry));
+
+-- radar_fuzz snaps a point to the center of its cell in a grid of roughly
+-- meters by meters. It mirrors privacy.Fuzz so fuzzed positions can be
+-- aggregated in SQL; the two must be kept in sync.
+CREATE OR REPLACE FUNCTION radar_fuzz(geom geometry, meters integer)
+RETURNS geometry AS $$
+	SELECT ST_SetSRID(ST_MakePoint(CASE WHEN lon > 180 THEN lon - 360 ELSE lon END, lat), 4326)
+	FROM (
+		SELECT lat, (floor(ST_X(geom) / lon_step) + 0.5) * lon_step AS lon
+		FROM (
+			SELECT lat, lat_step / greatest(0.01, cos(radians(lat))) AS lon_step
+			FROM (
+				SELECT least(90, greatest(-90, (floor(ST_Y(geom) / lat_step) + 0.5) * lat_step)) AS lat, lat_step
+				FROM (SELECT meters / 111320.0::double precision AS lat_step) grid
+			) latitude
+		) longitude
+	) fuzzed
+$$ LANGUAGE sql IMMUTABLE STRICT;
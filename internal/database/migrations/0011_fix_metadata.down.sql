@@ -0,0 +1,13 @@
+ALTER TABLE location_history
+	DROP COLUMN IF EXISTS device_timestamp,
+	DROP COLUMN IF EXISTS course_degrees,
+	DROP COLUMN IF EXISTS speed_mps,
+	DROP COLUMN IF EXISTS altitude_meters,
+	DROP COLUMN IF EXISTS accuracy_meters;
+
+ALTER TABLE user_radar
+	DROP COLUMN IF EXISTS device_timestamp,
+	DROP COLUMN IF EXISTS course_degrees,
+	DROP COLUMN IF EXISTS speed_mps,
+	DROP COLUMN IF EXISTS altitude_meters,
+	DROP COLUMN IF EXISTS accuracy_meters;
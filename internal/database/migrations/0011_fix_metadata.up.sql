@@ -0,0 +1,14 @@
+-- Optional detail reported by the device with each fix
+ALTER TABLE user_radar
+	ADD COLUMN IF NOT EXISTS accuracy_meters DOUBLE PRECISION CHECK (accuracy_meters >= 0),
+	ADD COLUMN IF NOT EXISTS altitude_meters DOUBLE PRECISION,
+	ADD COLUMN IF NOT EXISTS speed_mps DOUBLE PRECISION CHECK (speed_mps >= 0),
+	ADD COLUMN IF NOT EXISTS course_degrees DOUBLE PRECISION CHECK (course_degrees >= 0 AND course_degrees < 360),
+	ADD COLUMN IF NOT EXISTS device_timestamp TIMESTAMP;
+
+ALTER TABLE location_history
+	ADD COLUMN IF NOT EXISTS accuracy_meters DOUBLE PRECISION CHECK (accuracy_meters >= 0),
+	ADD COLUMN IF NOT EXISTS altitude_meters DOUBLE PRECISION,
+	ADD COLUMN IF NOT EXISTS speed_mps DOUBLE PRECISION CHECK (speed_mps >= 0),
+	ADD COLUMN IF NOT EXISTS course_degrees DOUBLE PRECISION CHECK (course_degrees >= 0 AND course_degrees < 360),
+	ADD COLUMN IF NOT EXISTS device_timestamp TIMESTAMP;
@@ -0,0 +1,15 @@
+-- Location updates that implied an implausible speed, kept for moderation
+CREATE TABLE IF NOT EXISTS location_flags (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	from_location GEOGRAPHY(POINT, 4326) NOT NULL,
+	to_location GEOGRAPHY(POINT, 4326) NOT NULL,
+	distance_meters DOUBLE PRECISION NOT NULL,
+	elapsed_seconds DOUBLE PRECISION NOT NULL,
+	speed_kmh DOUBLE PRECISION NOT NULL,
+	action VARCHAR(10) NOT NULL CHECK (action IN ('rejected', 'flagged')),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_flags_user_created_at ON location_flags(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_location_flags_created_at ON location_flags(created_at DESC);
@@ -0,0 +1,6 @@
+-- Token buckets shared by all API instances, stored as the time each bucket
+-- is full again (GCRA); timestamptz so instances agree regardless of zone
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+	key VARCHAR(255) PRIMARY KEY,
+	tat TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations_EmbeddedFilesAreValid(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "migration versions must be contiguous")
+		assert.NotEmpty(t, m.Up)
+		assert.NotEmpty(t, m.Down)
+	}
+}
+
+func TestLoadMigrations_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_second.up.sql":   {Data: []byte("SELECT 2")},
+		"migrations/0002_second.down.sql": {Data: []byte("SELECT -2")},
+		"migrations/0001_first.up.sql":    {Data: []byte("SELECT 1")},
+		"migrations/0001_first.down.sql":  {Data: []byte("SELECT -1")},
+	}
+
+	migrations, err := loadMigrations(fsys)
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "first", Up: "SELECT 1", Down: "SELECT -1"}, migrations[0])
+	assert.Equal(t, Migration{Version: 2, Name: "second", Up: "SELECT 2", Down: "SELECT -2"}, migrations[1])
+}
+
+func TestLoadMigrations_InvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"missing down", fstest.MapFS{
+			"migrations/0001_first.up.sql": {Data: []byte("SELECT 1")},
+		}},
+		{"missing version", fstest.MapFS{
+			"migrations/first.up.sql":   {Data: []byte("SELECT 1")},
+			"migrations/first.down.sql": {Data: []byte("SELECT -1")},
+		}},
+		{"unknown direction", fstest.MapFS{
+			"migrations/0001_first.sql": {Data: []byte("SELECT 1")},
+		}},
+		{"duplicate version", fstest.MapFS{
+			"migrations/0001_first.up.sql":   {Data: []byte("SELECT 1")},
+			"migrations/0001_first.down.sql": {Data: []byte("SELECT -1")},
+			"migrations/0001_other.up.sql":   {Data: []byte("SELECT 1")},
+			"migrations/0001_other.down.sql": {Data: []byte("SELECT -1")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+// EarthRadiusMeters is the mean Earth radius used for spherical calculations
+const EarthRadiusMeters = 6371008.8
+
+// DistanceMeters returns the great-circle distance between two WGS84 points
+// using the haversine formula
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Point is a WGS84 coordinate
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Polygon is a list of linear rings; the first is the exterior and any
+// following rings are holes. Each ring is closed (first point == last point).
+type Polygon [][]Point
+
+// PolygonFromGeoJSON converts GeoJSON polygon coordinates ([lon, lat]
+// positions) into a Polygon, validating ring structure and coordinate ranges
+func PolygonFromGeoJSON(coordinates [][][]float64) (Polygon, error) {
+	if len(coordinates) == 0 {
+		return nil, errors.New("polygon needs at least one ring")
+	}
+
+	polygon := make(Polygon, 0, len(coordinates))
+	for _, ring := range coordinates {
+		if len(ring) < 4 {
+			return nil, errors.New("polygon rings need at least four positions")
+		}
+
+		points := make([]Point, 0, len(ring))
+		for _, position := range ring {
+			if len(position) < 2 {
+				return nil, errors.New("positions need a longitude and a latitude")
+			}
+			lon, lat := position[0], position[1]
+			if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+				return nil, errors.New("position out of range")
+			}
+			points = append(points, Point{Latitude: lat, Longitude: lon})
+		}
+
+		if points[0] != points[len(points)-1] {
+			return nil, errors.New("polygon rings must be closed")
+		}
+		polygon = append(polygon, points)
+	}
+
+	return polygon, nil
+}
+
+// BoundingBox returns the rectangle between two corners as a closed polygon
+func BoundingBox(min, max Point) Polygon {
+	return Polygon{{
+		{Latitude: min.Latitude, Longitude: min.Longitude},
+		{Latitude: min.Latitude, Longitude: max.Longitude},
+		{Latitude: max.Latitude, Longitude: max.Longitude},
+		{Latitude: max.Latitude, Longitude: min.Longitude},
+		{Latitude: min.Latitude, Longitude: min.Longitude},
+	}}
+}
+
+// Bounds returns the south-west and north-east corners of the exterior ring
+func (p Polygon) Bounds() (Point, Point) {
+	if len(p) == 0 || len(p[0]) == 0 {
+		return Point{}, Point{}
+	}
+
+	min, max := p[0][0], p[0][0]
+	for _, pt := range p[0][1:] {
+		min.Latitude = math.Min(min.Latitude, pt.Latitude)
+		min.Longitude = math.Min(min.Longitude, pt.Longitude)
+		max.Latitude = math.Max(max.Latitude, pt.Latitude)
+		max.Longitude = math.Max(max.Longitude, pt.Longitude)
+	}
+	return min, max
+}
+
+// Contains reports whether pt lies inside the polygon, treating coordinates
+// as planar like PostGIS ST_Within on geometry
+func (p Polygon) Contains(pt Point) bool {
+	if len(p) == 0 || !ringContains(p[0], pt) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains is the even-odd ray casting test
+func ringContains(ring []Point, pt Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Latitude > pt.Latitude) != (b.Latitude > pt.Latitude) &&
+			pt.Longitude < (b.Longitude-a.Longitude)*(pt.Latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistanceMeters(t *testing.T) {
+	// Berlin to Munich is roughly 504 km
+	assert.InDelta(t, 504_000, DistanceMeters(52.5200, 13.4050, 48.1351, 11.5820), 2_000)
+	assert.Zero(t, DistanceMeters(52.5200, 13.4050, 52.5200, 13.4050))
+}
+
+func TestPolygon_Contains(t *testing.T) {
+	// A square around central Berlin with a hole in the middle
+	polygon, err := PolygonFromGeoJSON([][][]float64{
+		{{13.3, 52.4}, {13.5, 52.4}, {13.5, 52.6}, {13.3, 52.6}, {13.3, 52.4}},
+		{{13.39, 52.49}, {13.41, 52.49}, {13.41, 52.51}, {13.39, 52.51}, {13.39, 52.49}},
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, polygon.Contains(Point{Latitude: 52.45, Longitude: 13.35}))
+	assert.False(t, polygon.Contains(Point{Latitude: 52.50, Longitude: 13.40}), "point in hole")
+	assert.False(t, polygon.Contains(Point{Latitude: 48.1351, Longitude: 11.5820}))
+}
+
+func TestBoundingBox_Bounds(t *testing.T) {
+	box := BoundingBox(Point{Latitude: 52.4, Longitude: 13.3}, Point{Latitude: 52.6, Longitude: 13.5})
+	assert.True(t, box.Contains(Point{Latitude: 52.5, Longitude: 13.4}))
+	assert.False(t, box.Contains(Point{Latitude: 52.7, Longitude: 13.4}))
+
+	min, max := box.Bounds()
+	assert.Equal(t, Point{Latitude: 52.4, Longitude: 13.3}, min)
+	assert.Equal(t, Point{Latitude: 52.6, Longitude: 13.5}, max)
+}
+
+func TestPolygonFromGeoJSON_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		coordinates [][][]float64
+	}{
+		{"no rings", nil},
+		{"too few positions", [][][]float64{{{0, 0}, {1, 0}, {0, 0}}}},
+		{"not closed", [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}},
+		{"out of range", [][][]float64{{{0, 0}, {200, 0}, {1, 1}, {0, 0}}}},
+		{"short position", [][][]float64{{{0, 0}, {1}, {1, 1}, {0, 0}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PolygonFromGeoJSON(tt.coordinates)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"api-backend/internal/auth"
+	"api-backend/internal/models"
+	"api-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	users  repository.UserRepository
+	tokens *auth.TokenManager
+}
+
+func NewAuthHandler(users repository.UserRepository, tokens *auth.TokenManager) *AuthHandler {
+	return &AuthHandler{users: users, tokens: tokens}
+}
+
+// Login exchanges email and password for an access/refresh token pair
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.users.GetByEmail(c.Request.Context(), req.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		internalError(c, "failed to verify credentials", err)
+		return
+	}
+
+	if user == nil || user.PasswordHash == "" || !auth.CheckPassword(user.PasswordHash, req.Password) {
+		respondError(c, http.StatusUnauthorized, "invalid email or password")
+		return
+	}
+
+	tokens, err := h.tokens.IssuePair(user.ID)
+	if err != nil {
+		internalError(c, "failed to issue tokens", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := h.tokens.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "invalid or expired refresh token")
+		return
+	}
+
+	// The account may have been deleted since the refresh token was issued
+	userExists, err := h.users.Exists(c.Request.Context(), userID)
+	if err != nil {
+		internalError(c, "failed to verify user", err)
+		return
+	}
+	if !userExists {
+		respondError(c, http.StatusUnauthorized, "invalid or expired refresh token")
+		return
+	}
+
+	tokens, err := h.tokens.IssuePair(userID)
+	if err != nil {
+		internalError(c, "failed to issue tokens", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
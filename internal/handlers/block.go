@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"api-backend/internal/logging"
+	"api-backend/internal/middleware"
+	"api-backend/internal/realtime"
+	"api-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BlockHandler struct {
+	users repository.UserRepository
+	radar repository.RadarRepository
+	hub   realtime.Hub
+}
+
+func NewBlockHandler(users repository.UserRepository, radar repository.RadarRepository, hub realtime.Hub) *BlockHandler {
+	return &BlockHandler{users: users, radar: radar, hub: hub}
+}
+
+// Block makes the authenticated user and the user in the path invisible to
+// each other on the radar. Blocking an already blocked user is a no-op.
+func (h *BlockHandler) Block(c *gin.Context) {
+	blockerID, blockedID, ok := h.blockPair(c)
+	if !ok {
+		return
+	}
+
+	err := h.users.Block(c.Request.Context(), blockerID, blockedID)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to block user", err)
+		return
+	}
+
+	h.hub.PublishBlock(realtime.BlockEvent{BlockerID: blockerID, BlockedID: blockedID, Blocked: true})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "user blocked"})
+}
+
+// Unblock removes the authenticated user's block on the user in the path.
+// They only reappear to each other if the other side has no block either.
+func (h *BlockHandler) Unblock(c *gin.Context) {
+	blockerID, blockedID, ok := h.blockPair(c)
+	if !ok {
+		return
+	}
+
+	err := h.users.Unblock(c.Request.Context(), blockerID, blockedID)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "block not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to unblock user", err)
+		return
+	}
+
+	// The block is already gone, so failing to notify streams is logged
+	// rather than failing the request
+	stillBlocked, err := h.users.BlockedUserIDs(c.Request.Context(), blockerID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load blocks", "user_id", blockerID, "error", err)
+	} else if !slices.Contains(stillBlocked, blockedID) {
+		h.hub.PublishBlock(realtime.BlockEvent{BlockerID: blockerID, BlockedID: blockedID, Blocked: false})
+
+		// Re-announce both positions so open streams show them right away
+		h.republish(c, blockerID)
+		h.republish(c, blockedID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user unblocked"})
+}
+
+// blockPair reads the authenticated blocker and the target user from the
+// request, writing an error response when they are missing or the same
+func (h *BlockHandler) blockPair(c *gin.Context) (int, int, bool) {
+	blockedID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return 0, 0, false
+	}
+
+	blockerID, ok := middleware.UserID(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "authentication required")
+		return 0, 0, false
+	}
+
+	if blockerID == blockedID {
+		respondError(c, http.StatusBadRequest, "cannot block yourself")
+		return 0, 0, false
+	}
+
+	return blockerID, blockedID, true
+}
+
+func (h *BlockHandler) republish(c *gin.Context, userID int) {
+	radar, err := h.radar.GetLocation(c.Request.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load location", "user_id", userID, "error", err)
+		return
+	}
+
+	settings, err := h.radar.GetPrivacy(c.Request.Context(), userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load privacy settings", "user_id", userID, "error", err)
+		return
+	}
+
+	publishLocation(h.hub, radar, settings)
+}
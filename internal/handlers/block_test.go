@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"api-backend/internal/middleware"
+	"api-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBlockTestRouter(t *testing.T) (*gin.Engine, *testStore) {
+	gin.SetMode(gin.TestMode)
+
+	store := newTestStore()
+
+	router := gin.New()
+	radarHandler := NewRadarHandler(store.users, store.radar, store.geofences, store.flags, store.hub, store.spoofing)
+	blockHandler := NewBlockHandler(store.users, store.radar, store.hub)
+
+	api := router.Group("/api/v1", middleware.Auth(testTokens))
+	{
+		api.POST("/users/:id/blocks", blockHandler.Block)
+		api.DELETE("/users/:id/blocks", blockHandler.Unblock)
+		api.POST("/radar/location", radarHandler.UpdateLocation)
+		api.GET("/radar/nearby", radarHandler.GetNearbyUsers)
+		api.GET("/radar/stream", radarHandler.StreamNearbyUsers)
+	}
+
+	return router, store
+}
+
+func blocksPath(userID int) string {
+	return "/api/v1/users/" + strconv.Itoa(userID) + "/blocks"
+}
+
+// nearbyIDs returns the user IDs viewerID sees around central Berlin
+func nearbyIDs(t *testing.T, router *gin.Engine, viewerID int) []int {
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10", nil, viewerID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Users []models.NearbyUser `json:"users"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	ids := []int{}
+	for _, user := range response.Users {
+		ids = append(ids, user.UserID)
+	}
+	return ids
+}
+
+func TestBlock_HidesBothSidesFromNearby(t *testing.T) {
+	router, store := setupBlockTestRouter(t)
+
+	alice := createTestUser(t, store, "alice@example.com")
+	bob := createTestUser(t, store, "bob@example.com")
+	carol := createTestUser(t, store, "carol@example.com")
+	for _, userID := range []int{alice, bob, carol} {
+		placeTestUser(t, store, userID, 52.5210, 13.4060, true)
+	}
+
+	assert.Equal(t, []int{bob, carol}, nearbyIDs(t, router, alice), "the viewer never sees themselves")
+
+	w := performJSON(t, router, http.MethodPost, blocksPath(bob), nil, alice)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Blocking again is a no-op
+	w = performJSON(t, router, http.MethodPost, blocksPath(bob), nil, alice)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	assert.Equal(t, []int{carol}, nearbyIDs(t, router, alice))
+	assert.Equal(t, []int{carol}, nearbyIDs(t, router, bob), "the blocked user cannot see the blocker either")
+
+	w = performJSON(t, router, http.MethodDelete, blocksPath(bob), nil, alice)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{bob, carol}, nearbyIDs(t, router, alice))
+}
+
+func TestBlock_Errors(t *testing.T) {
+	router, store := setupBlockTestRouter(t)
+
+	alice := createTestUser(t, store, "alice@example.com")
+	bob := createTestUser(t, store, "bob@example.com")
+
+	w := performJSON(t, router, http.MethodPost, blocksPath(alice), nil, alice)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performJSON(t, router, http.MethodPost, blocksPath(999), nil, alice)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performJSON(t, router, http.MethodDelete, blocksPath(bob), nil, alice)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performJSON(t, router, http.MethodPost, blocksPath(bob), nil, 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestBlock_UpdatesOpenStreams(t *testing.T) {
+	router, store := setupBlockTestRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	alice := createTestUser(t, store, "alice@example.com")
+	bob := createTestUser(t, store, "bob@example.com")
+	carol := createTestUser(t, store, "carol@example.com")
+	placeTestUser(t, store, alice, 52.5200, 13.4050, true)
+	placeTestUser(t, store, bob, 52.5210, 13.4060, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/radar/stream?latitude=52.5200&longitude=13.4050&radius=10", nil)
+	authorize(t, req, alice)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+
+	// Alice is inside her own area but only sees Bob
+	name, event := readSSEvent(t, scanner)
+	assert.Equal(t, "enter", name)
+	assert.Equal(t, bob, event.UserID)
+
+	w := performJSON(t, router, http.MethodPost, blocksPath(alice), nil, bob)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	name, event = readSSEvent(t, scanner)
+	assert.Equal(t, "leave", name)
+	assert.Equal(t, bob, event.UserID)
+
+	// Bob's updates no longer reach Alice; Carol's still do
+	for _, userID := range []int{bob, carol} {
+		w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location", models.UpdateLocationRequest{
+			Latitude:  52.5220,
+			Longitude: 13.4070,
+		}, userID)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	name, event = readSSEvent(t, scanner)
+	assert.Equal(t, "enter", name)
+	assert.Equal(t, carol, event.UserID)
+
+	w = performJSON(t, router, http.MethodDelete, blocksPath(alice), nil, bob)
+	assert.Equal(t, http.StatusOK, w.Code)
+	name, event = readSSEvent(t, scanner)
+	assert.Equal(t, "enter", name)
+	assert.Equal(t, bob, event.UserID, "unblocking re-announces the user")
+}
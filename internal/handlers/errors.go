@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"api-backend/internal/database"
+	"api-backend/internal/logging"
+	"api-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is the non-standard status, taken from nginx,
+// recorded for requests whose client went away before the response
+const statusClientClosedRequest = 499
+
+// respondError writes an error response carrying the request ID
+func respondError(c *gin.Context, status int, message string) {
+	c.JSON(status, middleware.ErrorBody(c, message))
+}
+
+// internalError logs err with the request's logger and responds with a 500
+// that only carries message, so internals do not leak to clients. Queries
+// cut short because the client left are answered with 499, and queries that
+// ran out of time with 504.
+func internalError(c *gin.Context, message string, err error) {
+	logger := logging.FromContext(c.Request.Context())
+
+	switch {
+	case errors.Is(c.Request.Context().Err(), context.Canceled):
+		logger.Info(message+": client closed request", "error", err)
+		respondError(c, statusClientClosedRequest, "client closed request")
+	case database.IsTimeout(err):
+		logger.Warn(message+": query timed out", "error", err)
+		respondError(c, http.StatusGatewayTimeout, "request timed out")
+	default:
+		logger.Error(message, "error", err)
+		respondError(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInternalError_MapsCancellation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		err            error
+		clientGone     bool
+		expectedStatus int
+		expectedError  string
+	}{
+		{"server error", errors.New("connection refused"), false, http.StatusInternalServerError, "failed to fetch nearby users"},
+		{"query deadline", context.DeadlineExceeded, false, http.StatusGatewayTimeout, "request timed out"},
+		{"statement timeout", &pq.Error{Code: "57014"}, false, http.StatusGatewayTimeout, "request timed out"},
+		{"client closed", &pq.Error{Code: "57014"}, true, statusClientClosedRequest, "client closed request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.clientGone {
+				cancel()
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/radar/nearby", nil).WithContext(ctx)
+
+			internalError(c, "failed to fetch nearby users", tt.err)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedError, body["error"])
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"api-backend/internal/models"
+)
+
+// userETag derives a strong entity tag from the user's updated_at, which
+// changes on every write
+func userETag(user *models.User) string {
+	return `"` + strconv.FormatInt(user.UpdatedAt.UnixMicro(), 36) + `"`
+}
+
+// etagMatches implements the strong comparison If-Match requires: the header
+// matches when it is "*" or lists the current tag. Weak tags never match.
+func etagMatches(ifMatch, current string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == current {
+			return true
+		}
+	}
+	return false
+}
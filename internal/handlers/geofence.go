@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/middleware"
+	"api-backend/internal/models"
+	"api-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeofenceHandler manages personal geofences. Fences are private to their
+// owner, so other users' fences are reported as not found.
+type GeofenceHandler struct {
+	geofences repository.GeofenceRepository
+}
+
+func NewGeofenceHandler(geofences repository.GeofenceRepository) *GeofenceHandler {
+	return &GeofenceHandler{geofences: geofences}
+}
+
+func (h *GeofenceHandler) Create(c *gin.Context) {
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req models.GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fence, err := geofenceFromRequest(req, userID)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	created, err := h.geofences.Create(c.Request.Context(), *fence)
+	if err != nil {
+		internalError(c, "failed to create geofence", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *GeofenceHandler) List(c *gin.Context) {
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	fences, err := h.geofences.ListByOwner(c.Request.Context(), userID)
+	if err != nil {
+		internalError(c, "failed to fetch geofences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":     len(fences),
+		"geofences": fences,
+	})
+}
+
+func (h *GeofenceHandler) GetByID(c *gin.Context) {
+	fence, ok := h.ownedGeofence(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, fence)
+}
+
+// Update replaces a geofence's name and shape
+func (h *GeofenceHandler) Update(c *gin.Context) {
+	existing, ok := h.ownedGeofence(c)
+	if !ok {
+		return
+	}
+
+	var req models.GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fence, err := geofenceFromRequest(req, existing.OwnerID)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	fence.ID = existing.ID
+
+	updated, err := h.geofences.Update(c.Request.Context(), *fence)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "geofence not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to update geofence", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *GeofenceHandler) Delete(c *gin.Context) {
+	fence, ok := h.ownedGeofence(c)
+	if !ok {
+		return
+	}
+
+	err := h.geofences.Delete(c.Request.Context(), fence.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "geofence not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to delete geofence", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "geofence deleted successfully"})
+}
+
+// ListEvents returns the newest enter/exit events for one geofence
+func (h *GeofenceHandler) ListEvents(c *gin.Context) {
+	fence, ok := h.ownedGeofence(c)
+	if !ok {
+		return
+	}
+
+	h.listEvents(c, repository.GeofenceEventFilter{GeofenceID: fence.ID})
+}
+
+// ListUserEvents returns the newest enter/exit events for a user across all
+// of their geofences. Users may only read their own events.
+func (h *GeofenceHandler) ListUserEvents(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	if currentUserID, ok := middleware.UserID(c); !ok || currentUserID != id {
+		respondError(c, http.StatusForbidden, "cannot read another user's geofence events")
+		return
+	}
+
+	h.listEvents(c, repository.GeofenceEventFilter{UserID: id})
+}
+
+func (h *GeofenceHandler) listEvents(c *gin.Context, filter repository.GeofenceEventFilter) {
+	var req models.GeofenceEventsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit = pageSize(req.Limit)
+
+	events, err := h.geofences.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		internalError(c, "failed to fetch geofence events", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":  len(events),
+		"events": events,
+	})
+}
+
+// ownedGeofence loads the :id geofence and writes an error response unless it
+// belongs to the authenticated user
+func (h *GeofenceHandler) ownedGeofence(c *gin.Context) (*models.Geofence, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid geofence id")
+		return nil, false
+	}
+
+	fence, err := h.geofences.GetByID(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		internalError(c, "failed to fetch geofence", err)
+		return nil, false
+	}
+
+	userID, _ := middleware.UserID(c)
+	if fence == nil || fence.OwnerID != userID {
+		respondError(c, http.StatusNotFound, "geofence not found")
+		return nil, false
+	}
+
+	return fence, true
+}
+
+// geofenceFromRequest keeps only the fields relevant to the fence type and
+// validates polygon geometry
+func geofenceFromRequest(req models.GeofenceRequest, ownerID int) (*models.Geofence, error) {
+	fence := &models.Geofence{
+		OwnerID: ownerID,
+		Name:    req.Name,
+		Type:    req.Type,
+	}
+
+	switch req.Type {
+	case models.GeofenceCircle:
+		fence.Latitude = req.Latitude
+		fence.Longitude = req.Longitude
+		fence.RadiusMeters = req.RadiusMeters
+	case models.GeofencePolygon:
+		if _, err := geo.PolygonFromGeoJSON(req.Polygon.Coordinates); err != nil {
+			return nil, err
+		}
+		fence.Polygon = req.Polygon
+	}
+
+	return fence, nil
+}
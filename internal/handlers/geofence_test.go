@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"api-backend/internal/middleware"
+	"api-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupGeofenceTestRouter(t *testing.T) (*gin.Engine, *testStore) {
+	gin.SetMode(gin.TestMode)
+
+	store := newTestStore()
+
+	router := gin.New()
+	geofenceHandler := NewGeofenceHandler(store.geofences)
+	radarHandler := NewRadarHandler(store.users, store.radar, store.geofences, store.flags, store.hub, store.spoofing)
+
+	api := router.Group("/api/v1", middleware.Auth(testTokens))
+	{
+		api.POST("/radar/location", radarHandler.UpdateLocation)
+		api.GET("/users/:id/geofence-events", geofenceHandler.ListUserEvents)
+
+		api.POST("/geofences", geofenceHandler.Create)
+		api.GET("/geofences", geofenceHandler.List)
+		api.GET("/geofences/:id", geofenceHandler.GetByID)
+		api.PUT("/geofences/:id", geofenceHandler.Update)
+		api.DELETE("/geofences/:id", geofenceHandler.Delete)
+		api.GET("/geofences/:id/events", geofenceHandler.ListEvents)
+	}
+
+	return router, store
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+// berlinSquare is a polygon around central Berlin
+var berlinSquare = &models.GeoJSONPolygon{
+	Type: "Polygon",
+	Coordinates: [][][]float64{
+		{{13.3, 52.4}, {13.5, 52.4}, {13.5, 52.6}, {13.3, 52.6}, {13.3, 52.4}},
+	},
+}
+
+func createTestGeofence(t *testing.T, router *gin.Engine, req models.GeofenceRequest, userID int) models.Geofence {
+	w := performJSON(t, router, http.MethodPost, "/api/v1/geofences", req, userID)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var fence models.Geofence
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &fence))
+	return fence
+}
+
+func TestCreateGeofence_CircleAndPolygon(t *testing.T) {
+	router, store := setupGeofenceTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+
+	circle := createTestGeofence(t, router, models.GeofenceRequest{
+		Name:         "Home",
+		Type:         models.GeofenceCircle,
+		Latitude:     floatPtr(52.5200),
+		Longitude:    floatPtr(13.4050),
+		RadiusMeters: floatPtr(200),
+	}, me)
+	assert.Equal(t, me, circle.OwnerID)
+	assert.Nil(t, circle.Polygon)
+
+	polygon := createTestGeofence(t, router, models.GeofenceRequest{
+		Name:    "Berlin",
+		Type:    models.GeofencePolygon,
+		Polygon: berlinSquare,
+		// Circle fields are dropped for polygons
+		RadiusMeters: floatPtr(10),
+	}, me)
+	assert.NotNil(t, polygon.Polygon)
+	assert.Nil(t, polygon.RadiusMeters)
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/geofences", nil, me)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":2`)
+}
+
+func TestCreateGeofence_Invalid(t *testing.T) {
+	router, store := setupGeofenceTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+
+	tests := []struct {
+		name string
+		req  models.GeofenceRequest
+	}{
+		{"unknown type", models.GeofenceRequest{Name: "x", Type: "triangle"}},
+		{"circle without radius", models.GeofenceRequest{Name: "x", Type: models.GeofenceCircle, Latitude: floatPtr(1), Longitude: floatPtr(1)}},
+		{"circle with negative radius", models.GeofenceRequest{Name: "x", Type: models.GeofenceCircle, Latitude: floatPtr(1), Longitude: floatPtr(1), RadiusMeters: floatPtr(-5)}},
+		{"polygon without geometry", models.GeofenceRequest{Name: "x", Type: models.GeofencePolygon}},
+		{"open polygon ring", models.GeofenceRequest{Name: "x", Type: models.GeofencePolygon, Polygon: &models.GeoJSONPolygon{
+			Type:        "Polygon",
+			Coordinates: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performJSON(t, router, http.MethodPost, "/api/v1/geofences", tt.req, me)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestGeofence_PrivateToOwner(t *testing.T) {
+	router, store := setupGeofenceTestRouter(t)
+	owner := createTestUser(t, store, "owner@example.com")
+	other := createTestUser(t, store, "other@example.com")
+
+	fence := createTestGeofence(t, router, models.GeofenceRequest{
+		Name: "Berlin", Type: models.GeofencePolygon, Polygon: berlinSquare,
+	}, owner)
+	path := fmt.Sprintf("/api/v1/geofences/%d", fence.ID)
+
+	assert.Equal(t, http.StatusNotFound, performJSON(t, router, http.MethodGet, path, nil, other).Code)
+	assert.Equal(t, http.StatusNotFound, performJSON(t, router, http.MethodDelete, path, nil, other).Code)
+	assert.Equal(t, http.StatusNotFound, performJSON(t, router, http.MethodGet, path+"/events", nil, other).Code)
+
+	w := performJSON(t, router, http.MethodPut, path, models.GeofenceRequest{
+		Name: "Renamed", Type: models.GeofencePolygon, Polygon: berlinSquare,
+	}, owner)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Renamed")
+
+	assert.Equal(t, http.StatusOK, performJSON(t, router, http.MethodDelete, path, nil, owner).Code)
+	assert.Equal(t, http.StatusNotFound, performJSON(t, router, http.MethodGet, path, nil, owner).Code)
+}
+
+func TestGeofence_EnterExitEvents(t *testing.T) {
+	router, store := setupGeofenceTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+	other := createTestUser(t, store, "other@example.com")
+
+	home := createTestGeofence(t, router, models.GeofenceRequest{
+		Name:         "Home",
+		Type:         models.GeofenceCircle,
+		Latitude:     floatPtr(52.5200),
+		Longitude:    floatPtr(13.4050),
+		RadiusMeters: floatPtr(500),
+	}, me)
+	city := createTestGeofence(t, router, models.GeofenceRequest{
+		Name: "Berlin", Type: models.GeofencePolygon, Polygon: berlinSquare,
+	}, me)
+
+	moveTo := func(userID int, latitude, longitude float64) {
+		w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location", models.UpdateLocationRequest{
+			Latitude:  latitude,
+			Longitude: longitude,
+		}, userID)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	moveTo(me, 52.5201, 13.4051)    // first fix: enters both fences
+	moveTo(me, 52.5202, 13.4052)    // still inside both: no events
+	moveTo(me, 52.4500, 13.3500)    // leaves home, still in the city
+	moveTo(me, 48.1351, 11.5820)    // Munich: leaves the city
+	moveTo(other, 52.5200, 13.4050) // other users never trigger my fences
+
+	var response struct {
+		Count  int                    `json:"count"`
+		Events []models.GeofenceEvent `json:"events"`
+	}
+
+	w := performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/users/%d/geofence-events", me), nil, me)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 4, response.Count)
+
+	// Newest first
+	assert.Equal(t, "exit", response.Events[0].Type)
+	assert.Equal(t, city.ID, response.Events[0].GeofenceID)
+
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/geofences/%d/events", home.ID), nil, me)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Count)
+	assert.Equal(t, "exit", response.Events[0].Type)
+	assert.Equal(t, "enter", response.Events[1].Type)
+
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/users/%d/geofence-events", me), nil, other)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
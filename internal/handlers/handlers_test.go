@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-backend/internal/auth"
+	"api-backend/internal/models"
+	"api-backend/internal/realtime"
+	"api-backend/internal/repository/memory"
+	"api-backend/internal/spoofing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testTokens = auth.NewTokenManager("test-secret", time.Hour, 24*time.Hour)
+
+// testPresenceTTL is long enough that positions never go stale mid-test
+const testPresenceTTL = time.Hour
+
+// testMaxSpeedKmh only stops jumps between continents, since tests post
+// fixes milliseconds apart
+const testMaxSpeedKmh = 1e7
+
+type testStore struct {
+	users     *memory.UserRepository
+	radar     *memory.RadarRepository
+	geofences *memory.GeofenceRepository
+	flags     *memory.LocationFlagRepository
+	hub       *realtime.MemoryHub
+	spoofing  *spoofing.Checker
+}
+
+func newTestStore() *testStore {
+	users := memory.NewUserRepository()
+	return &testStore{
+		users:     users,
+		radar:     memory.NewRadarRepository(users, testPresenceTTL),
+		geofences: memory.NewGeofenceRepository(),
+		flags:     memory.NewLocationFlagRepository(),
+		hub:       realtime.NewMemoryHub(16),
+		spoofing:  spoofing.NewChecker(testMaxSpeedKmh, spoofing.ModeReject),
+	}
+}
+
+func createTestUser(t *testing.T, store *testStore, email string) int {
+	user, err := store.users.Create(context.Background(), email, "")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user.ID
+}
+
+func placeTestUser(t *testing.T, store *testStore, userID int, latitude, longitude float64, isActive bool) {
+	if _, err := store.radar.UpsertLocation(context.Background(), userID, models.LocationPoint{Latitude: latitude, Longitude: longitude, IsActive: isActive}, nil); err != nil {
+		t.Fatalf("Failed to place test user: %v", err)
+	}
+}
+
+func authorize(t *testing.T, req *http.Request, userID int) {
+	tokens, err := testTokens.IssuePair(userID)
+	if err != nil {
+		t.Fatalf("Failed to issue test token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+}
+
+// performJSON sends body as JSON to router, authenticated as userID when it
+// is non-zero
+func performJSON(t *testing.T, router *gin.Engine, method, path string, body interface{}, userID int) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("Failed to encode request body: %v", err)
+		}
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		authorize(t, req, userID)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -1,31 +1,106 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"api-backend/internal/database"
+	"api-backend/internal/logging"
 
 	"github.com/gin-gonic/gin"
 )
 
+// readinessTimeout bounds all readiness checks together
+const readinessTimeout = 3 * time.Second
+
+// healthCheck is one dependency the readiness probe verifies
+type healthCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
 type HealthHandler struct {
-	db *database.Database
+	checks   []healthCheck
+	draining atomic.Bool
 }
 
 func NewHealthHandler(db *database.Database) *HealthHandler {
-	return &HealthHandler{db: db}
+	// The pool goes first so the other checks' connections do not count
+	return newHealthHandler(
+		healthCheck{name: "pool", run: db.CheckPool},
+		healthCheck{name: "database", run: db.Ping},
+		healthCheck{name: "postgis", run: db.CheckPostGIS},
+		healthCheck{name: "migrations", run: db.CheckMigrations},
+	)
+}
+
+func newHealthHandler(checks ...healthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+// Drain makes the readiness probe fail from now on, so load balancers stop
+// routing to an instance that is shutting down
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up and serving; it checks no dependency
+// so a database outage does not get the instance restarted
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 }
 
-func (h *HealthHandler) Check(c *gin.Context) {
-	if err := h.db.Health(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status": "unhealthy",
-			"error":  "database connection failed",
-		})
+// Ready runs every dependency check in turn and responds 503 if any fails,
+// with each check's status and latency in the body
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
-	})
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	healthy := true
+	results := make(gin.H, len(h.checks))
+	for _, check := range h.checks {
+		start := time.Now()
+		err := check.run(ctx)
+		result := gin.H{
+			"status":     "healthy",
+			"latency_ms": math.Round(float64(time.Since(start).Microseconds())/10) / 100,
+		}
+
+		if err != nil {
+			healthy = false
+			result["status"] = "unhealthy"
+			result["error"] = checkMessage(err)
+			logging.FromContext(c.Request.Context()).Warn("readiness check failed", "check", check.name, "error", err)
+		}
+		results[check.name] = result
+	}
+
+	status, code := "healthy", http.StatusOK
+	if !healthy {
+		status, code = "unhealthy", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
+
+// checkMessage describes a failed check without exposing driver errors,
+// which can name hosts and addresses
+func checkMessage(err error) string {
+	var checkErr *database.CheckError
+	switch {
+	case errors.As(err, &checkErr):
+		return checkErr.Message
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out"
+	default:
+		return "check failed"
+	}
 }
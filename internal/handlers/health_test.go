@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"api-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupHealthTestRouter(checks ...healthCheck) (*gin.Engine, *HealthHandler) {
+	gin.SetMode(gin.TestMode)
+
+	handler := newHealthHandler(checks...)
+	router := gin.New()
+	router.GET("/health/live", handler.Live)
+	router.GET("/health/ready", handler.Ready)
+	return router, handler
+}
+
+func passing(ctx context.Context) error { return nil }
+
+type readinessResponse struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Status    string   `json:"status"`
+		LatencyMs *float64 `json:"latency_ms"`
+		Error     string   `json:"error"`
+	} `json:"checks"`
+}
+
+func getReadiness(t *testing.T, router *gin.Engine) (int, readinessResponse) {
+	w := performJSON(t, router, http.MethodGet, "/health/ready", nil, 0)
+	var response readinessResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestHealthReady_AllHealthy(t *testing.T) {
+	router, _ := setupHealthTestRouter(
+		healthCheck{name: "database", run: passing},
+		healthCheck{name: "postgis", run: passing},
+	)
+
+	code, response := getReadiness(t, router)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "healthy", response.Status)
+	require.Len(t, response.Checks, 2)
+	assert.Equal(t, "healthy", response.Checks["database"].Status)
+	assert.NotNil(t, response.Checks["database"].LatencyMs)
+	assert.Empty(t, response.Checks["database"].Error)
+}
+
+func TestHealthReady_FailingCheck(t *testing.T) {
+	router, _ := setupHealthTestRouter(
+		healthCheck{name: "database", run: func(ctx context.Context) error {
+			return errors.New("dial tcp 10.0.0.3:5432: connect: connection refused")
+		}},
+		healthCheck{name: "migrations", run: func(ctx context.Context) error {
+			return &database.CheckError{Message: "schema is at version 12, expected 13"}
+		}},
+		healthCheck{name: "pool", run: passing},
+	)
+
+	code, response := getReadiness(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unhealthy", response.Status)
+
+	// Driver errors are not passed on, check findings are
+	assert.Equal(t, "unhealthy", response.Checks["database"].Status)
+	assert.Equal(t, "check failed", response.Checks["database"].Error)
+	assert.Equal(t, "schema is at version 12, expected 13", response.Checks["migrations"].Error)
+	assert.Equal(t, "healthy", response.Checks["pool"].Status)
+}
+
+func TestHealth_Draining(t *testing.T) {
+	router, handler := setupHealthTestRouter(healthCheck{name: "database", run: passing})
+
+	handler.Drain()
+
+	code, response := getReadiness(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting_down", response.Status)
+
+	// The process is still alive while it drains
+	w := performJSON(t, router, http.MethodGet, "/health/live", nil, 0)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
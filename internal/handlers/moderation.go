@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"api-backend/internal/models"
+	"api-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationHandler serves data recorded for moderation review; its routes
+// must be restricted to admins
+type ModerationHandler struct {
+	flags repository.LocationFlagRepository
+}
+
+func NewModerationHandler(flags repository.LocationFlagRepository) *ModerationHandler {
+	return &ModerationHandler{flags: flags}
+}
+
+// ListLocationFlags returns a user's implausible location updates, newest first
+func (h *ModerationHandler) ListLocationFlags(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	var req models.LocationFlagsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flags, err := h.flags.ListByUser(c.Request.Context(), id, pageSize(req.Limit))
+	if err != nil {
+		internalError(c, "failed to fetch location flags", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(flags),
+		"flags": flags,
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"api-backend/internal/middleware"
+	"api-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupModerationTestRouter(t *testing.T) (*gin.Engine, *testStore) {
+	gin.SetMode(gin.TestMode)
+
+	store := newTestStore()
+
+	router := gin.New()
+	router.Use(middleware.Admins([]int{testAdminID}))
+	moderationHandler := NewModerationHandler(store.flags)
+
+	admin := router.Group("/api/v1/admin", middleware.Auth(testTokens), middleware.RequireAdmin())
+	{
+		admin.GET("/users/:id/location-flags", moderationHandler.ListLocationFlags)
+	}
+
+	return router, store
+}
+
+func TestListLocationFlags(t *testing.T) {
+	router, store := setupModerationTestRouter(t)
+	userID := createTestUser(t, store, "teleporter@example.com")
+	other := createTestUser(t, store, "other@example.com")
+
+	for _, flag := range []models.LocationFlag{
+		{UserID: userID, SpeedKmh: 5000, Action: models.FlagActionRejected},
+		{UserID: other, SpeedKmh: 3000, Action: models.FlagActionFlagged},
+		{UserID: userID, SpeedKmh: 9000, Action: models.FlagActionFlagged},
+	} {
+		if _, err := store.flags.Create(context.Background(), flag); err != nil {
+			t.Fatalf("Failed to create flag: %v", err)
+		}
+	}
+
+	path := "/api/v1/admin/users/" + strconv.Itoa(userID) + "/location-flags"
+	w := performJSON(t, router, http.MethodGet, path, nil, testAdminID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Count int                   `json:"count"`
+		Flags []models.LocationFlag `json:"flags"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Count)
+	assert.Equal(t, 9000.0, response.Flags[0].SpeedKmh, "newest first")
+	assert.Equal(t, 5000.0, response.Flags[1].SpeedKmh)
+
+	w = performJSON(t, router, http.MethodGet, path+"?limit=1", nil, testAdminID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Count)
+}
+
+func TestListLocationFlags_RequiresAdmin(t *testing.T) {
+	router, store := setupModerationTestRouter(t)
+	userID := createTestUser(t, store, "me@example.com")
+
+	path := "/api/v1/admin/users/" + strconv.Itoa(userID) + "/location-flags"
+	w := performJSON(t, router, http.MethodGet, path, nil, userID)
+	assert.Equal(t, http.StatusForbidden, w.Code, "not even for the user's own flags")
+
+	w = performJSON(t, router, http.MethodGet, path, nil, 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performJSON(t, router, http.MethodGet, "/api/v1/admin/users/abc/location-flags", nil, testAdminID)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"api-backend/internal/repository"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// userCursor is the JSON payload behind the opaque next_cursor token. The
+// sort order is included so a cursor cannot be replayed against the
+// opposite ordering.
+type userCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        int       `json:"i"`
+	Ascending bool      `json:"a,omitempty"`
+}
+
+func encodeUserCursor(cursor repository.UserCursor, ascending bool) string {
+	payload, _ := json.Marshal(userCursor{
+		CreatedAt: cursor.CreatedAt.UTC(),
+		ID:        cursor.ID,
+		Ascending: ascending,
+	})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeUserCursor(token string, ascending bool) (*repository.UserCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var cursor userCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	if cursor.Ascending != ascending {
+		return nil, errInvalidCursor
+	}
+
+	return &repository.UserCursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID}, nil
+}
+
+// pageSize applies the default and caps the requested limit
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
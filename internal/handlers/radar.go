@@ -1,39 +1,91 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
-	"api-backend/internal/database"
+	"api-backend/internal/geo"
+	"api-backend/internal/logging"
+	"api-backend/internal/metrics"
+	"api-backend/internal/middleware"
 	"api-backend/internal/models"
+	"api-backend/internal/privacy"
+	"api-backend/internal/realtime"
+	"api-backend/internal/repository"
+	"api-backend/internal/spoofing"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// maxAreaDiagonalKm bounds bounding box and polygon queries, matching the
+// 500 km maximum radius of nearby searches
+const maxAreaDiagonalKm = 1000
+
+// maxFixClockSkew is how far ahead of the server clock a device timestamp
+// may be
+const maxFixClockSkew = time.Minute
+
+// errImplausibleMove aborts a location update the speed check rejected
+var errImplausibleMove = errors.New("implausible move")
+
+// clusterCellsPerTile is how many cluster cells fit along the side of a map
+// tile, and maxClusterCells bounds how many cells one query may cover
+const (
+	clusterCellsPerTile = 4
+	maxClusterCells     = 10000
+)
+
+// streamKeepAlive is how often an idle stream sends an SSE comment so proxies
+// and load balancers do not close the connection
+const streamKeepAlive = 25 * time.Second
+
 type RadarHandler struct {
-	db *database.Database
+	users     repository.UserRepository
+	radar     repository.RadarRepository
+	geofences repository.GeofenceRepository
+	flags     repository.LocationFlagRepository
+	hub       realtime.Hub
+	spoofing  *spoofing.Checker
 }
 
-func NewRadarHandler(db *database.Database) *RadarHandler {
-	return &RadarHandler{db: db}
+func NewRadarHandler(users repository.UserRepository, radar repository.RadarRepository, geofences repository.GeofenceRepository, flags repository.LocationFlagRepository, hub realtime.Hub, checker *spoofing.Checker) *RadarHandler {
+	return &RadarHandler{users: users, radar: radar, geofences: geofences, flags: flags, hub: hub, spoofing: checker}
 }
 
-// UpdateLocation updates or creates a user's location in the radar system
+// UpdateLocation updates or creates the authenticated user's location in the radar system
 func (h *RadarHandler) UpdateLocation(c *gin.Context) {
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
 	var req models.UpdateLocationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Check if user exists
-	var userExists bool
-	err := h.db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", req.UserID).Scan(&userExists)
+	userExists, err := h.users.Exists(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify user"})
+		internalError(c, "failed to verify user", err)
 		return
 	}
 	if !userExists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		respondError(c, http.StatusNotFound, "user not found")
 		return
 	}
 
@@ -43,84 +95,673 @@ func (h *RadarHandler) UpdateLocation(c *gin.Context) {
 		isActive = *req.IsActive
 	}
 
-	// Upsert location using ON CONFLICT
-	query := `
-		INSERT INTO user_radar (user_id, location, is_active, updated_at)
-		VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), $4, CURRENT_TIMESTAMP)
-		ON CONFLICT (user_id)
-		DO UPDATE SET
-			location = ST_SetSRID(ST_MakePoint($2, $3), 4326),
-			is_active = $4,
-			updated_at = CURRENT_TIMESTAMP
-		RETURNING id, user_id, ST_Y(location::geometry) as latitude, ST_X(location::geometry) as longitude, is_active, created_at, updated_at
-	`
-
-	var radar models.UserRadar
-	err = h.db.DB.QueryRow(
-		query,
-		req.UserID,
-		req.Longitude,
-		req.Latitude,
-		isActive,
-	).Scan(&radar.ID, &radar.UserID, &radar.Latitude, &radar.Longitude, &radar.IsActive, &radar.CreatedAt, &radar.UpdatedAt)
+	fix := models.LocationPoint{
+		Latitude:        req.Latitude,
+		Longitude:       req.Longitude,
+		IsActive:        isActive,
+		FixMetadata:     req.FixMetadata,
+		DeviceTimestamp: req.DeviceTimestamp,
+		RecordedAt:      time.Now(),
+	}
 
+	// The repository runs the check against the position it replaces, so
+	// concurrent updates cannot both be compared with the same previous fix.
+	// That position is also remembered for geofence transitions.
+	var previous *geo.Point
+	var flag *models.LocationFlag
+	radar, err := h.radar.UpsertLocation(c.Request.Context(), userID, fix, func(current *models.UserRadar) error {
+		if current == nil {
+			return nil
+		}
+		previous = &geo.Point{Latitude: current.Latitude, Longitude: current.Longitude}
+		if flag = h.spoofing.Check(livePoint(current), fix); flag != nil && flag.Action == models.FlagActionRejected {
+			return errImplausibleMove
+		}
+		return nil
+	})
+	if flag != nil {
+		h.recordFlag(c.Request.Context(), userID, flag)
+	}
+	if errors.Is(err, errImplausibleMove) {
+		respondError(c, http.StatusUnprocessableEntity, "location update implies an implausible speed")
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update location"})
+		internalError(c, "failed to update location", err)
 		return
 	}
+	metrics.LocationUpdates.WithLabelValues("single").Inc()
+
+	// The location is already stored, so a geofence failure is logged rather
+	// than failing the update
+	position := geo.Point{Latitude: radar.Latitude, Longitude: radar.Longitude}
+	if _, err := h.geofences.RecordTransitions(c.Request.Context(), userID, previous, position); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to evaluate geofences", "user_id", userID, "error", err)
+	}
+
+	// Streams mask positions themselves, but need the settings to do so;
+	// without them the update is not published rather than leaked
+	settings, err := h.radar.GetPrivacy(c.Request.Context(), userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load privacy settings", "user_id", userID, "error", err)
+	} else {
+		publishLocation(h.hub, radar, settings)
+	}
 
 	c.JSON(http.StatusOK, radar)
 }
 
-// GetNearbyUsers finds all active registered users within a specified radius
+// BatchUpdateLocation stores fixes a device buffered while offline. Fixes not
+// newer than the current position are ignored, so retried uploads are
+// harmless, and the newest fix becomes the live position. Each fix is checked
+// for plausibility against the one before it; in reject mode implausible fixes
+// are dropped and the rest of the batch is stored.
+func (h *RadarHandler) BatchUpdateLocation(c *gin.Context) {
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req models.BatchLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A fix from the future would hold the live position until that time
+	latest := time.Now().Add(maxFixClockSkew)
+	fixes := make([]models.LocationPoint, 0, len(req.Fixes))
+	for _, fix := range req.Fixes {
+		if fix.RecordedAt.After(latest) {
+			respondError(c, http.StatusBadRequest, "recorded_at must not be in the future")
+			return
+		}
+
+		isActive := true
+		if fix.IsActive != nil {
+			isActive = *fix.IsActive
+		}
+		recordedAt := fix.RecordedAt
+		fixes = append(fixes, models.LocationPoint{
+			Latitude:        fix.Latitude,
+			Longitude:       fix.Longitude,
+			IsActive:        isActive,
+			FixMetadata:     fix.FixMetadata,
+			DeviceTimestamp: &recordedAt,
+			RecordedAt:      recordedAt,
+		})
+	}
+
+	userExists, err := h.users.Exists(c.Request.Context(), userID)
+	if err != nil {
+		internalError(c, "failed to verify user", err)
+		return
+	}
+	if !userExists {
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	// As for single updates, fixes are checked against the position the
+	// repository holds locked while it writes the batch
+	var previous *geo.Point
+	var flags []*models.LocationFlag
+	rejected := 0
+	radar, accepted, err := h.radar.AppendLocations(c.Request.Context(), userID, fixes, func(current *models.UserRadar, fixes []models.LocationPoint) []models.LocationPoint {
+		if current != nil {
+			previous = &geo.Point{Latitude: current.Latitude, Longitude: current.Longitude}
+		}
+		var plausible []models.LocationPoint
+		plausible, flags = h.checkFixes(current, fixes)
+		rejected = len(fixes) - len(plausible)
+		return plausible
+	})
+	for _, flag := range flags {
+		h.recordFlag(c.Request.Context(), userID, flag)
+	}
+	// ErrNotFound only means there was no position and no fix to store
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		internalError(c, "failed to update location", err)
+		return
+	}
+	metrics.LocationUpdates.WithLabelValues("batch").Add(float64(accepted))
+
+	// Only the move to the newest fix is checked against geofences and
+	// published; intermediate fixes are history by now
+	if accepted > 0 {
+		position := geo.Point{Latitude: radar.Latitude, Longitude: radar.Longitude}
+		if _, err := h.geofences.RecordTransitions(c.Request.Context(), userID, previous, position); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to evaluate geofences", "user_id", userID, "error", err)
+		}
+
+		settings, err := h.radar.GetPrivacy(c.Request.Context(), userID)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to load privacy settings", "user_id", userID, "error", err)
+		} else {
+			publishLocation(h.hub, radar, settings)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accepted": accepted,
+		"ignored":  len(fixes) - accepted - rejected,
+		"rejected": rejected,
+		"location": radar,
+	})
+}
+
+// checkFixes checks each fix newer than current against the fix before it
+// and returns the fixes to store, plus a flag for every implausible one.
+// Rejected fixes are skipped, so the next fix is compared with the last one
+// kept.
+func (h *RadarHandler) checkFixes(current *models.UserRadar, fixes []models.LocationPoint) ([]models.LocationPoint, []*models.LocationFlag) {
+	sorted := make([]models.LocationPoint, len(fixes))
+	copy(sorted, fixes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].RecordedAt.Before(sorted[j].RecordedAt)
+	})
+
+	var last *models.LocationPoint
+	if current != nil {
+		point := livePoint(current)
+		last = &point
+	}
+
+	var flags []*models.LocationFlag
+	plausible := make([]models.LocationPoint, 0, len(sorted))
+	for i := range sorted {
+		fix := sorted[i]
+		// Fixes the repository will ignore are not worth flagging
+		if current != nil && !fix.RecordedAt.After(current.UpdatedAt) {
+			plausible = append(plausible, fix)
+			continue
+		}
+
+		if last != nil {
+			if flag := h.spoofing.Check(*last, fix); flag != nil {
+				flags = append(flags, flag)
+				if flag.Action == models.FlagActionRejected {
+					continue
+				}
+			}
+		}
+
+		plausible = append(plausible, fix)
+		last = &sorted[i]
+	}
+
+	return plausible, flags
+}
+
+// recordFlag stores a flag for moderation; a failure is logged rather than
+// failing the update, which has been checked either way
+func (h *RadarHandler) recordFlag(ctx context.Context, userID int, flag *models.LocationFlag) {
+	flag.UserID = userID
+	metrics.LocationFlags.WithLabelValues(flag.Action).Inc()
+	if _, err := h.flags.Create(ctx, *flag); err != nil {
+		logging.FromContext(ctx).Error("failed to record location flag", "user_id", userID, "error", err)
+	}
+}
+
+// livePoint returns a live position as the fix it was last updated with
+func livePoint(radar *models.UserRadar) models.LocationPoint {
+	return models.LocationPoint{
+		Latitude:        radar.Latitude,
+		Longitude:       radar.Longitude,
+		IsActive:        radar.IsActive,
+		FixMetadata:     radar.FixMetadata,
+		DeviceTimestamp: radar.DeviceTimestamp,
+		RecordedAt:      radar.UpdatedAt,
+	}
+}
+
+// GetPrivacy returns the authenticated user's radar privacy settings
+func (h *RadarHandler) GetPrivacy(c *gin.Context) {
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	settings, err := h.radar.GetPrivacy(c.Request.Context(), userID)
+	if err != nil {
+		internalError(c, "failed to fetch privacy settings", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdatePrivacy replaces the authenticated user's radar privacy settings and
+// re-publishes their position so open streams apply the new settings
+func (h *RadarHandler) UpdatePrivacy(c *gin.Context) {
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req models.UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The grid size only means something for fuzzed visibility
+	fuzzMeters := 0
+	if req.Visibility == models.VisibilityFuzzed {
+		fuzzMeters = req.FuzzMeters
+		if fuzzMeters == 0 {
+			fuzzMeters = privacy.DefaultFuzzMeters
+		}
+	}
+
+	settings, err := h.radar.UpdatePrivacy(c.Request.Context(), userID, models.PrivacySettings{
+		Visibility: req.Visibility,
+		FuzzMeters: fuzzMeters,
+	})
+	if err != nil {
+		internalError(c, "failed to update privacy settings", err)
+		return
+	}
+
+	radar, err := h.radar.GetLocation(c.Request.Context(), userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		logging.FromContext(c.Request.Context()).Error("failed to load location", "user_id", userID, "error", err)
+	}
+	if radar != nil {
+		publishLocation(h.hub, radar, settings)
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// publishLocation notifies stream subscribers of a user's position and settings
+func publishLocation(hub realtime.Hub, radar *models.UserRadar, settings *models.PrivacySettings) {
+	hub.Publish(realtime.LocationEvent{
+		UserID:     radar.UserID,
+		Latitude:   radar.Latitude,
+		Longitude:  radar.Longitude,
+		IsActive:   radar.IsActive,
+		Visibility: settings.Visibility,
+		FuzzMeters: settings.FuzzMeters,
+		UpdatedAt:  radar.UpdatedAt,
+	})
+}
+
+// GetNearbyUsers finds active registered users within a radius, or the
+// closest ones in nearest mode, masking each according to their privacy
+// settings. The caller and users blocked either way are left out. Pages use
+// limit/offset rather than a distance cursor, since a cursor would have to
+// carry the exact distance of fuzzed users.
 func (h *RadarHandler) GetNearbyUsers(c *gin.Context) {
 	var req models.NearbyUsersRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Query uses PostGIS ST_DWithin for efficient spatial search
-	// ST_DWithin uses meters for geography type
-	// Returns distance in kilometers using ST_Distance
-	query := `
-		SELECT
-			ur.user_id,
-			u.email,
-			ST_Y(ur.location::geometry) as latitude,
-			ST_X(ur.location::geometry) as longitude,
-			ST_Distance(ur.location, ST_SetSRID(ST_MakePoint($1, $2), 4326)) / 1000 as distance_km,
-			ur.updated_at
-		FROM user_radar ur
-		JOIN users u ON ur.user_id = u.id
-		WHERE ur.is_active = true
-		AND ST_DWithin(
-			ur.location,
-			ST_SetSRID(ST_MakePoint($1, $2), 4326),
-			$3 * 1000
-		)
-		ORDER BY distance_km ASC
-	`
-
-	rows, err := h.db.DB.Query(query, req.Longitude, req.Latitude, req.Radius)
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	viewerID, _ := middleware.UserID(c)
+
+	origin := geo.Point{Latitude: req.Latitude, Longitude: req.Longitude}
+	nearbyUsers, nextOffset, ok := h.findPage(c, repository.NearbyFilter{
+		Latitude:          req.Latitude,
+		Longitude:         req.Longitude,
+		RadiusKm:          req.Radius,
+		Nearest:           req.Mode == models.NearbyModeNearest,
+		Limit:             req.Limit,
+		Offset:            req.Offset,
+		ViewerID:          viewerID,
+		MaxAccuracyMeters: req.MaxAccuracy,
+	})
+	if !ok {
+		return
+	}
+	nearbyUsers = privacy.MaskAll(origin, nearbyUsers)
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":       len(nearbyUsers),
+		"users":       nearbyUsers,
+		"next_offset": nextOffset,
+	})
+}
+
+// GetUsersInBoundingBox returns the users inside a map viewport, closest to
+// its center first
+func (h *RadarHandler) GetUsersInBoundingBox(c *gin.Context) {
+	var req models.BoundingBoxRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	min, max, ok := boundingBoxCorners(c, req.BoundingBox)
+	if !ok {
+		return
+	}
+	h.respondArea(c, geo.BoundingBox(min, max), req.Limit, req.Offset)
+}
+
+// GetClusters aggregates the users inside a map viewport into grid cells sized
+// for the zoom level, so zoomed-out maps need not download every user
+func (h *RadarHandler) GetClusters(c *gin.Context) {
+	var req models.ClusterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	min, max, ok := boundingBoxCorners(c, req.BoundingBox)
+	if !ok {
+		return
+	}
+
+	// Tiles span 360/2^zoom degrees of longitude; splitting each into
+	// clusterCellsPerTile cells per side gives roughly 64 pixel cells
+	cellDegrees := 360 / math.Pow(2, float64(*req.Zoom)) / clusterCellsPerTile
+	columns := math.Floor(max.Longitude/cellDegrees) - math.Floor(min.Longitude/cellDegrees) + 1
+	rows := math.Floor(max.Latitude/cellDegrees) - math.Floor(min.Latitude/cellDegrees) + 1
+	if columns*rows > maxClusterCells {
+		respondError(c, http.StatusBadRequest, "bounding box is too large for the zoom level")
+		return
+	}
+	viewerID, _ := middleware.UserID(c)
+
+	clusters, err := h.radar.Clusters(c.Request.Context(), repository.ClusterFilter{
+		Min:         min,
+		Max:         max,
+		CellDegrees: cellDegrees,
+		ViewerID:    viewerID,
+	})
+	if err != nil {
+		internalError(c, "failed to fetch clusters", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":        len(clusters),
+		"clusters":     clusters,
+		"cell_degrees": cellDegrees,
+	})
+}
+
+// boundingBoxCorners returns the south-west and north-east corners of box,
+// writing a 400 response and returning false if they are swapped
+func boundingBoxCorners(c *gin.Context, box models.BoundingBox) (geo.Point, geo.Point, bool) {
+	if *box.MinLatitude > *box.MaxLatitude || *box.MinLongitude > *box.MaxLongitude {
+		respondError(c, http.StatusBadRequest, "min coordinates must not exceed max coordinates")
+		return geo.Point{}, geo.Point{}, false
+	}
+
+	return geo.Point{Latitude: *box.MinLatitude, Longitude: *box.MinLongitude},
+		geo.Point{Latitude: *box.MaxLatitude, Longitude: *box.MaxLongitude}, true
+}
+
+// FindUsersInPolygon returns the users inside the GeoJSON polygon in the
+// request body, closest to the center of its bounding box first
+func (h *RadarHandler) FindUsersInPolygon(c *gin.Context) {
+	var page models.AreaPageRequest
+	if err := c.ShouldBindQuery(&page); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.GeoJSONPolygon
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	area, err := geo.PolygonFromGeoJSON(req.Coordinates)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch nearby users"})
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.respondArea(c, area, page.Limit, page.Offset)
+}
+
+// respondArea writes a page of the users inside area. The repository matches
+// users on their masked position and leaves out distance-only users, so a
+// tiny area cannot pinpoint someone more precisely than their privacy
+// settings allow.
+func (h *RadarHandler) respondArea(c *gin.Context, area geo.Polygon, limit, offset int) {
+	min, max := area.Bounds()
+	if geo.DistanceMeters(min.Latitude, min.Longitude, max.Latitude, max.Longitude) > maxAreaDiagonalKm*1000 {
+		respondError(c, http.StatusBadRequest, "area is too large")
+		return
+	}
+	viewerID, _ := middleware.UserID(c)
+
+	// Distances are measured from the center of the area
+	origin := geo.Point{
+		Latitude:  (min.Latitude + max.Latitude) / 2,
+		Longitude: (min.Longitude + max.Longitude) / 2,
+	}
+	users, nextOffset, ok := h.findPage(c, repository.NearbyFilter{
+		Latitude:  origin.Latitude,
+		Longitude: origin.Longitude,
+		Area:      area,
+		Limit:     limit,
+		Offset:    offset,
+		ViewerID:  viewerID,
+	})
+	if !ok {
 		return
 	}
-	defer rows.Close()
+	users = privacy.MaskAll(origin, users)
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":       len(users),
+		"users":       users,
+		"next_offset": nextOffset,
+	})
+}
+
+// findPage runs filter with its limit capped by pageSize and reports the
+// offset of the next page, if any. Results still hold exact positions. On
+// failure it writes the error response and returns false.
+func (h *RadarHandler) findPage(c *gin.Context, filter repository.NearbyFilter) ([]models.NearbyUser, *int, bool) {
+	// Fetch one extra row to learn whether another page exists
+	limit := pageSize(filter.Limit)
+	filter.Limit = limit + 1
+
+	users, err := h.radar.FindNearby(c.Request.Context(), filter)
+	if err != nil {
+		internalError(c, "failed to fetch nearby users", err)
+		return nil, nil, false
+	}
+
+	var nextOffset *int
+	if len(users) > limit {
+		users = users[:limit]
+		next := filter.Offset + limit
+		nextOffset = &next
+	}
+
+	return users, nextOffset, true
+}
 
-	nearbyUsers := []models.NearbyUser{}
-	for rows.Next() {
-		var user models.NearbyUser
-		if err := rows.Scan(&user.UserID, &user.Email, &user.Latitude, &user.Longitude, &user.DistanceKm, &user.LastUpdateAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to scan nearby user"})
+// StreamNearbyUsers streams enter/move/leave events for users in the given
+// area as Server-Sent Events. It starts with an enter event for every user
+// already inside, so clients need no separate /nearby call. Like /nearby it
+// never reports the caller or users blocked either way.
+func (h *RadarHandler) StreamNearbyUsers(c *gin.Context) {
+	var req models.NearbyStreamRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	viewerID, _ := middleware.UserID(c)
+
+	// Subscribe before taking the snapshot so no update falls in between;
+	// the tracker turns any overlap into harmless move events
+	sub := h.hub.Subscribe()
+	defer sub.Close()
+
+	blockedIDs, err := h.users.BlockedUserIDs(c.Request.Context(), viewerID)
+	if err != nil {
+		internalError(c, "failed to fetch nearby users", err)
+		return
+	}
+
+	snapshot, err := h.radar.FindNearby(c.Request.Context(), repository.NearbyFilter{
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		RadiusKm:  req.Radius,
+		ViewerID:  viewerID,
+	})
+	if err != nil {
+		internalError(c, "failed to fetch nearby users", err)
+		return
+	}
+
+	tracker := realtime.NewAreaTracker(realtime.Area{
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		RadiusKm:  req.Radius,
+	})
+	tracker.Exclude(viewerID)
+	for _, id := range blockedIDs {
+		tracker.Exclude(id)
+	}
+
+	// A stream outlives the server's write timeout, so lift the deadline
+	// for this response; it ends when the client leaves or the hub closes
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	for _, user := range snapshot {
+		if event, ok := tracker.Apply(realtime.LocationEvent{
+			UserID:     user.UserID,
+			Latitude:   *user.Latitude,
+			Longitude:  *user.Longitude,
+			IsActive:   true,
+			Visibility: user.Visibility,
+			FuzzMeters: user.FuzzMeters,
+			UpdatedAt:  user.LastUpdateAt,
+		}); ok {
+			c.SSEvent(event.Type, event)
+		}
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case hubEvent, ok := <-sub.Events:
+			if !ok {
+				return false
+			}
+
+			var event models.RadarEvent
+			var send bool
+			switch {
+			case hubEvent.Location != nil:
+				event, send = tracker.Apply(*hubEvent.Location)
+			case hubEvent.Block != nil:
+				event, send = tracker.ApplyBlock(viewerID, *hubEvent.Block)
+			}
+			if send {
+				c.SSEvent(event.Type, event)
+			}
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
+
+// GetLocationHistory returns a user's location trail, optionally as a GeoJSON
+// LineString feature. Users may only read their own history.
+func (h *RadarHandler) GetLocationHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	if currentUserID, ok := middleware.UserID(c); !ok || currentUserID != id {
+		respondError(c, http.StatusForbidden, "cannot read another user's history")
+		return
+	}
+
+	var req models.LocationHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		respondError(c, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	points, err := h.radar.History(c.Request.Context(), id, repository.HistoryFilter{
+		From:  req.From,
+		To:    req.To,
+		Limit: limit,
+	})
+	if err != nil {
+		internalError(c, "failed to fetch location history", err)
+		return
+	}
+
+	if req.Format == "geojson" {
+		body, err := json.Marshal(historyFeature(id, points))
+		if err != nil {
+			internalError(c, "failed to encode location history", err)
 			return
 		}
-		nearbyUsers = append(nearbyUsers, user)
+		c.Data(http.StatusOK, "application/geo+json", body)
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"count": len(nearbyUsers),
-		"users": nearbyUsers,
+		"count":  len(points),
+		"points": points,
 	})
 }
+
+// historyFeature renders a trail as a GeoJSON LineString. A LineString needs
+// at least two positions, so shorter trails get a null geometry.
+func historyFeature(userID int, points []models.LocationPoint) models.GeoJSONFeature {
+	coordinates := make([][]float64, 0, len(points))
+	timestamps := make([]time.Time, 0, len(points))
+	for _, p := range points {
+		coordinates = append(coordinates, []float64{p.Longitude, p.Latitude})
+		timestamps = append(timestamps, p.RecordedAt)
+	}
+
+	feature := models.GeoJSONFeature{
+		Type: "Feature",
+		Properties: map[string]interface{}{
+			"user_id":    userID,
+			"count":      len(points),
+			"timestamps": timestamps,
+		},
+	}
+	if len(coordinates) >= 2 {
+		feature.Geometry = &models.GeoJSONGeometry{
+			Type:        "LineString",
+			Coordinates: coordinates,
+		}
+	}
+
+	return feature
+}
@@ -1,74 +1,61 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
-	"api-backend/internal/database"
+	"api-backend/internal/geo"
+	"api-backend/internal/metrics"
+	"api-backend/internal/middleware"
 	"api-backend/internal/models"
-	"api-backend/pkg/config"
+	"api-backend/internal/privacy"
+	"api-backend/internal/repository"
+	"api-backend/internal/spoofing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
-func setupRadarTestRouter(t *testing.T) (*gin.Engine, *database.Database) {
+func setupRadarTestRouter(t *testing.T) (*gin.Engine, *testStore) {
 	gin.SetMode(gin.TestMode)
 
-	cfg, err := config.Load()
-	if err != nil {
-		t.Fatalf("Failed to load config: %v", err)
-	}
-
-	db, err := database.New(cfg.DatabaseURL)
-	if err != nil {
-		t.Fatalf("Failed to connect to database: %v", err)
-	}
-
-	if err := db.RunMigrations(); err != nil {
-		t.Fatalf("Failed to run migrations: %v", err)
-	}
-
-	// Clean up test data
-	db.DB.Exec("DELETE FROM user_radar")
-	db.DB.Exec("DELETE FROM users")
+	store := newTestStore()
 
 	router := gin.New()
-	radarHandler := NewRadarHandler(db)
+	radarHandler := NewRadarHandler(store.users, store.radar, store.geofences, store.flags, store.hub, store.spoofing)
 
-	api := router.Group("/api/v1/radar")
+	api := router.Group("/api/v1/radar", middleware.Auth(testTokens))
 	{
 		api.POST("/location", radarHandler.UpdateLocation)
+		api.POST("/location/batch", radarHandler.BatchUpdateLocation)
 		api.GET("/nearby", radarHandler.GetNearbyUsers)
+		api.GET("/bbox", radarHandler.GetUsersInBoundingBox)
+		api.POST("/polygon", radarHandler.FindUsersInPolygon)
+		api.GET("/clusters", radarHandler.GetClusters)
+		api.GET("/stream", radarHandler.StreamNearbyUsers)
+		api.GET("/users/:id/history", radarHandler.GetLocationHistory)
+		api.GET("/privacy", radarHandler.GetPrivacy)
+		api.PUT("/privacy", radarHandler.UpdatePrivacy)
 	}
 
-	return router, db
-}
-
-func createTestUser(t *testing.T, db *database.Database, email string) int {
-	var userID int
-	err := db.DB.QueryRow(
-		"INSERT INTO users (email) VALUES ($1) RETURNING id",
-		email,
-	).Scan(&userID)
-	if err != nil {
-		t.Fatalf("Failed to create test user: %v", err)
-	}
-	return userID
+	return router, store
 }
 
 func TestUpdateLocation_Success(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
-	userID := createTestUser(t, db, "test@example.com")
+	userID := createTestUser(t, store, "test@example.com")
 
 	reqBody := models.UpdateLocationRequest{
-		UserID:    userID,
 		Latitude:  48.8566,
 		Longitude: 2.3522,
 	}
@@ -76,6 +63,7 @@ func TestUpdateLocation_Success(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 	req, _ := http.NewRequest(http.MethodPost, "/api/v1/radar/location", bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	authorize(t, req, userID)
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -91,34 +79,61 @@ func TestUpdateLocation_Success(t *testing.T) {
 	assert.True(t, response.IsActive)
 }
 
+func TestUpdateLocation_CountsUpdates(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+	userID := createTestUser(t, store, "metrics@example.com")
+
+	single := metrics.LocationUpdates.WithLabelValues("single")
+	batch := metrics.LocationUpdates.WithLabelValues("batch")
+	singleBefore, batchBefore := testutil.ToFloat64(single), testutil.ToFloat64(batch)
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location",
+		models.UpdateLocationRequest{Latitude: 52.52, Longitude: 13.405}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	recordedAt := time.Now().UTC()
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location/batch", models.BatchLocationRequest{
+		Fixes: []models.LocationFix{
+			{Latitude: 52.5201, Longitude: 13.4051, RecordedAt: recordedAt.Add(time.Second)},
+			{Latitude: 52.5202, Longitude: 13.4052, RecordedAt: recordedAt.Add(2 * time.Second)},
+		},
+	}, userID)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, singleBefore+1, testutil.ToFloat64(single))
+	assert.Equal(t, batchBefore+2, testutil.ToFloat64(batch))
+}
+
 func TestUpdateLocation_UpdateExisting(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
-	userID := createTestUser(t, db, "update@example.com")
+	userID := createTestUser(t, store, "update@example.com")
 
 	// First location
 	reqBody1 := models.UpdateLocationRequest{
-		UserID:    userID,
 		Latitude:  48.8566,
 		Longitude: 2.3522,
 	}
 	jsonBody1, _ := json.Marshal(reqBody1)
 	req1, _ := http.NewRequest(http.MethodPost, "/api/v1/radar/location", bytes.NewBuffer(jsonBody1))
 	req1.Header.Set("Content-Type", "application/json")
+	authorize(t, req1, userID)
 	w1 := httptest.NewRecorder()
 	router.ServeHTTP(w1, req1)
 	assert.Equal(t, http.StatusOK, w1.Code)
 
+	var first models.UserRadar
+	assert.NoError(t, json.Unmarshal(w1.Body.Bytes(), &first))
+
 	// Update location
 	reqBody2 := models.UpdateLocationRequest{
-		UserID:    userID,
 		Latitude:  52.5200,
 		Longitude: 13.4050,
 	}
 	jsonBody2, _ := json.Marshal(reqBody2)
 	req2, _ := http.NewRequest(http.MethodPost, "/api/v1/radar/location", bytes.NewBuffer(jsonBody2))
 	req2.Header.Set("Content-Type", "application/json")
+	authorize(t, req2, userID)
 	w2 := httptest.NewRecorder()
 	router.ServeHTTP(w2, req2)
 
@@ -130,21 +145,17 @@ func TestUpdateLocation_UpdateExisting(t *testing.T) {
 	assert.InDelta(t, 52.5200, response.Latitude, 0.0001)
 	assert.InDelta(t, 13.4050, response.Longitude, 0.0001)
 
-	// Verify only one record exists
-	var count int
-	db.DB.QueryRow("SELECT COUNT(*) FROM user_radar WHERE user_id = $1", userID).Scan(&count)
-	assert.Equal(t, 1, count)
+	// Verify the existing record was updated rather than a new one created
+	assert.Equal(t, first.ID, response.ID)
 }
 
 func TestUpdateLocation_SetInactive(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
-	userID := createTestUser(t, db, "inactive@example.com")
+	userID := createTestUser(t, store, "inactive@example.com")
 
 	isActive := false
 	reqBody := models.UpdateLocationRequest{
-		UserID:    userID,
 		Latitude:  48.8566,
 		Longitude: 2.3522,
 		IsActive:  &isActive,
@@ -153,6 +164,7 @@ func TestUpdateLocation_SetInactive(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 	req, _ := http.NewRequest(http.MethodPost, "/api/v1/radar/location", bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	authorize(t, req, userID)
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -166,11 +178,9 @@ func TestUpdateLocation_SetInactive(t *testing.T) {
 }
 
 func TestUpdateLocation_UserNotFound(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, _ := setupRadarTestRouter(t)
 
 	reqBody := models.UpdateLocationRequest{
-		UserID:    99999,
 		Latitude:  48.8566,
 		Longitude: 2.3522,
 	}
@@ -178,6 +188,7 @@ func TestUpdateLocation_UserNotFound(t *testing.T) {
 	jsonBody, _ := json.Marshal(reqBody)
 	req, _ := http.NewRequest(http.MethodPost, "/api/v1/radar/location", bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	authorize(t, req, 99999)
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -185,11 +196,28 @@ func TestUpdateLocation_UserNotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
+func TestUpdateLocation_Unauthorized(t *testing.T) {
+	router, _ := setupRadarTestRouter(t)
+
+	reqBody := models.UpdateLocationRequest{
+		Latitude:  48.8566,
+		Longitude: 2.3522,
+	}
+
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/radar/location", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
 func TestUpdateLocation_InvalidCoordinates(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
-	userID := createTestUser(t, db, "invalid@example.com")
+	userID := createTestUser(t, store, "invalid@example.com")
 
 	tests := []struct {
 		name      string
@@ -205,7 +233,6 @@ func TestUpdateLocation_InvalidCoordinates(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			reqBody := models.UpdateLocationRequest{
-				UserID:    userID,
 				Latitude:  tt.latitude,
 				Longitude: tt.longitude,
 			}
@@ -213,6 +240,7 @@ func TestUpdateLocation_InvalidCoordinates(t *testing.T) {
 			jsonBody, _ := json.Marshal(reqBody)
 			req, _ := http.NewRequest(http.MethodPost, "/api/v1/radar/location", bytes.NewBuffer(jsonBody))
 			req.Header.Set("Content-Type", "application/json")
+			authorize(t, req, userID)
 
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
@@ -222,29 +250,264 @@ func TestUpdateLocation_InvalidCoordinates(t *testing.T) {
 	}
 }
 
+func TestUpdateLocation_FixMetadata(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	userID := createTestUser(t, store, "metadata@example.com")
+	deviceTime := time.Now().Add(-5 * time.Second).UTC().Truncate(time.Millisecond)
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location", gin.H{
+		"latitude":         52.5200,
+		"longitude":        13.4050,
+		"accuracy_meters":  12.5,
+		"altitude_meters":  34.0,
+		"speed_mps":        1.4,
+		"course_degrees":   270.0,
+		"device_timestamp": deviceTime,
+	}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var radar models.UserRadar
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &radar))
+	assert.Equal(t, 12.5, *radar.AccuracyMeters)
+	assert.Equal(t, 34.0, *radar.AltitudeMeters)
+	assert.Equal(t, 1.4, *radar.SpeedMps)
+	assert.Equal(t, 270.0, *radar.CourseDegrees)
+	assert.True(t, radar.DeviceTimestamp.Equal(deviceTime))
+
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history", userID), nil, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history struct {
+		Points []models.LocationPoint `json:"points"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history.Points, 1)
+	assert.Equal(t, 12.5, *history.Points[0].AccuracyMeters)
+	assert.True(t, history.Points[0].DeviceTimestamp.Equal(deviceTime))
+
+	for _, body := range []gin.H{
+		{"latitude": 52.52, "longitude": 13.405, "accuracy_meters": -1.0},
+		{"latitude": 52.52, "longitude": 13.405, "speed_mps": -0.5},
+		{"latitude": 52.52, "longitude": 13.405, "course_degrees": 360.0},
+	} {
+		w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location", body, userID)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestBatchUpdateLocation(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	userID := createTestUser(t, store, "offline@example.com")
+	start := time.Now().Add(-10 * time.Minute).UTC().Truncate(time.Second)
+	fix := func(minutes int, latitude float64) gin.H {
+		return gin.H{"latitude": latitude, "longitude": 13.4050, "recorded_at": start.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	type batchResponse struct {
+		Accepted int              `json:"accepted"`
+		Ignored  int              `json:"ignored"`
+		Location models.UserRadar `json:"location"`
+	}
+
+	// Fixes arrive out of order; the newest becomes the live position
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location/batch", gin.H{
+		"fixes": []gin.H{fix(2, 52.5220), fix(0, 52.5200), fix(1, 52.5210)},
+	}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response batchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Accepted)
+	assert.Zero(t, response.Ignored)
+	assert.InDelta(t, 52.5220, response.Location.Latitude, 0.0001)
+	assert.True(t, response.Location.UpdatedAt.Equal(start.Add(2*time.Minute)))
+	assert.True(t, response.Location.DeviceTimestamp.Equal(start.Add(2*time.Minute)))
+
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history", userID), nil, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history struct {
+		Points []models.LocationPoint `json:"points"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history.Points, 3)
+	for i, latitude := range []float64{52.5200, 52.5210, 52.5220} {
+		assert.InDelta(t, latitude, history.Points[i].Latitude, 0.0001)
+	}
+
+	// Retrying the upload, plus a fix older than the live position, changes nothing
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location/batch", gin.H{
+		"fixes": []gin.H{fix(2, 52.5220), fix(1, 52.5210), fix(-1, 52.5190)},
+	}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response = batchResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Zero(t, response.Accepted)
+	assert.Equal(t, 3, response.Ignored)
+	assert.InDelta(t, 52.5220, response.Location.Latitude, 0.0001)
+
+	// Only the newer fix of a mixed batch is stored
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location/batch", gin.H{
+		"fixes": []gin.H{fix(1, 52.5210), fix(3, 52.5230)},
+	}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response = batchResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Accepted)
+	assert.Equal(t, 1, response.Ignored)
+	assert.InDelta(t, 52.5230, response.Location.Latitude, 0.0001)
+}
+
+func TestBatchUpdateLocation_Validation(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	userID := createTestUser(t, store, "offline@example.com")
+	now := time.Now().UTC()
+
+	for _, body := range []gin.H{
+		{},
+		{"fixes": []gin.H{}},
+		{"fixes": []gin.H{{"latitude": 52.52, "longitude": 13.405}}},
+		{"fixes": []gin.H{{"latitude": 91.0, "longitude": 13.405, "recorded_at": now}}},
+		{"fixes": []gin.H{{"latitude": 52.52, "longitude": 13.405, "recorded_at": now.Add(time.Hour)}}},
+	} {
+		w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location/batch", body, userID)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location/batch", gin.H{
+		"fixes": []gin.H{{"latitude": 52.52, "longitude": 13.405, "recorded_at": now}},
+	}, 9999)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// setupSpoofingTestRouter serves location updates with a realistic speed
+// limit, unlike the default test router
+func setupSpoofingTestRouter(t *testing.T, mode string) (*gin.Engine, *testStore) {
+	gin.SetMode(gin.TestMode)
+
+	store := newTestStore()
+	store.spoofing = spoofing.NewChecker(900, mode)
+
+	router := gin.New()
+	radarHandler := NewRadarHandler(store.users, store.radar, store.geofences, store.flags, store.hub, store.spoofing)
+
+	api := router.Group("/api/v1/radar", middleware.Auth(testTokens))
+	{
+		api.POST("/location", radarHandler.UpdateLocation)
+		api.POST("/location/batch", radarHandler.BatchUpdateLocation)
+	}
+
+	return router, store
+}
+
+func TestUpdateLocation_RejectsTeleport(t *testing.T) {
+	router, store := setupSpoofingTestRouter(t, spoofing.ModeReject)
+
+	userID := createTestUser(t, store, "spoofer@example.com")
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location", gin.H{"latitude": 52.5200, "longitude": 13.4050}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Walking distance is fine right away
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location", gin.H{"latitude": 52.5202, "longitude": 13.4052}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location", gin.H{"latitude": 35.6762, "longitude": 139.6503}, userID)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	current, err := store.radar.GetLocation(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.InDelta(t, 52.5202, current.Latitude, 0.0001)
+
+	flags, err := store.flags.ListByUser(context.Background(), userID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, flags, 1)
+	assert.Equal(t, models.FlagActionRejected, flags[0].Action)
+	assert.Equal(t, 35.6762, flags[0].ToLatitude)
+	assert.Greater(t, flags[0].SpeedKmh, 900.0)
+}
+
+func TestUpdateLocation_FlagsTeleport(t *testing.T) {
+	router, store := setupSpoofingTestRouter(t, spoofing.ModeFlag)
+
+	userID := createTestUser(t, store, "traveller@example.com")
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location", gin.H{"latitude": 52.5200, "longitude": 13.4050}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/location", gin.H{"latitude": 35.6762, "longitude": 139.6503}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	current, err := store.radar.GetLocation(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.InDelta(t, 35.6762, current.Latitude, 0.0001)
+
+	flags, err := store.flags.ListByUser(context.Background(), userID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, flags, 1)
+	assert.Equal(t, models.FlagActionFlagged, flags[0].Action)
+}
+
+func TestBatchUpdateLocation_DropsImplausibleFixes(t *testing.T) {
+	router, store := setupSpoofingTestRouter(t, spoofing.ModeReject)
+
+	userID := createTestUser(t, store, "offline@example.com")
+	start := time.Now().Add(-10 * time.Minute).UTC()
+	fix := func(minutes int, latitude, longitude float64) gin.H {
+		return gin.H{"latitude": latitude, "longitude": longitude, "recorded_at": start.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	// The Tokyo fix is dropped, and the fix after it is checked against the
+	// last one kept
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location/batch", gin.H{
+		"fixes": []gin.H{
+			fix(0, 52.5200, 13.4050),
+			fix(1, 52.5250, 13.4100),
+			fix(2, 35.6762, 139.6503),
+			fix(3, 52.5300, 13.4150),
+		},
+	}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Accepted int              `json:"accepted"`
+		Ignored  int              `json:"ignored"`
+		Rejected int              `json:"rejected"`
+		Location models.UserRadar `json:"location"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Accepted)
+	assert.Zero(t, response.Ignored)
+	assert.Equal(t, 1, response.Rejected)
+	assert.InDelta(t, 52.5300, response.Location.Latitude, 0.0001)
+
+	flags, err := store.flags.ListByUser(context.Background(), userID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, flags, 1)
+	assert.Equal(t, 35.6762, flags[0].ToLatitude)
+}
+
 func TestGetNearbyUsers_Success(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
 	// Create test users with locations
 	// Berlin: 52.5200° N, 13.4050° E
 	// Munich: 48.1351° N, 11.5820° E (distance ~504 km from Berlin)
 	// Hamburg: 53.5511° N, 9.9937° E (distance ~255 km from Berlin)
 
-	user1 := createTestUser(t, db, "berlin@example.com")
-	user2 := createTestUser(t, db, "munich@example.com")
-	user3 := createTestUser(t, db, "hamburg@example.com")
+	user1 := createTestUser(t, store, "berlin@example.com")
+	user2 := createTestUser(t, store, "munich@example.com")
+	user3 := createTestUser(t, store, "hamburg@example.com")
 
 	// Add locations
-	db.DB.Exec("INSERT INTO user_radar (user_id, location, is_active) VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), true)",
-		user1, 13.4050, 52.5200)
-	db.DB.Exec("INSERT INTO user_radar (user_id, location, is_active) VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), true)",
-		user2, 11.5820, 48.1351)
-	db.DB.Exec("INSERT INTO user_radar (user_id, location, is_active) VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), true)",
-		user3, 9.9937, 53.5511)
+	placeTestUser(t, store, user1, 52.5200, 13.4050, true)
+	placeTestUser(t, store, user2, 48.1351, 11.5820, true)
+	placeTestUser(t, store, user3, 53.5511, 9.9937, true)
 
 	// Search from Berlin with 300km radius (should find Berlin and Hamburg, not Munich)
+	viewer := createTestUser(t, store, "viewer@example.com")
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=300", nil)
+	authorize(t, req, viewer)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -267,22 +530,45 @@ func TestGetNearbyUsers_Success(t *testing.T) {
 	assert.Less(t, firstDistance, 300.0)
 }
 
+func TestGetNearbyUsers_ReturnsProfileNotEmail(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "viewer@example.com")
+	neighbour := createTestUser(t, store, "neighbour@example.com")
+	_, err := store.users.Update(context.Background(), neighbour, repository.UserUpdate{
+		Profile: &models.Profile{DisplayName: "Neighbour", Bio: "Hi"},
+	}, nil)
+	assert.NoError(t, err)
+	placeTestUser(t, store, neighbour, 52.5210, 13.4060, true)
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "neighbour@example.com")
+
+	var response struct {
+		Users []models.NearbyUser `json:"users"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Users, 1)
+	assert.Equal(t, "Neighbour", response.Users[0].Profile.DisplayName)
+	assert.Equal(t, "Hi", response.Users[0].Profile.Bio)
+}
+
 func TestGetNearbyUsers_ExcludesInactive(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
-	user1 := createTestUser(t, db, "active@example.com")
-	user2 := createTestUser(t, db, "inactive@example.com")
+	user1 := createTestUser(t, store, "active@example.com")
+	user2 := createTestUser(t, store, "inactive@example.com")
 
 	// Add active user
-	db.DB.Exec("INSERT INTO user_radar (user_id, location, is_active) VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), true)",
-		user1, 13.4050, 52.5200)
+	placeTestUser(t, store, user1, 52.5200, 13.4050, true)
 
 	// Add inactive user
-	db.DB.Exec("INSERT INTO user_radar (user_id, location, is_active) VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), false)",
-		user2, 13.4100, 52.5210)
+	placeTestUser(t, store, user2, 52.5210, 13.4100, false)
 
+	viewer := createTestUser(t, store, "viewer@example.com")
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10", nil)
+	authorize(t, req, viewer)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -297,17 +583,16 @@ func TestGetNearbyUsers_ExcludesInactive(t *testing.T) {
 }
 
 func TestGetNearbyUsers_NoUsersInRadius(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
-	user1 := createTestUser(t, db, "faraway@example.com")
+	user1 := createTestUser(t, store, "faraway@example.com")
 
 	// Add user far from search point
-	db.DB.Exec("INSERT INTO user_radar (user_id, location, is_active) VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), true)",
-		user1, -122.4194, 37.7749) // San Francisco
+	placeTestUser(t, store, user1, 37.7749, -122.4194, true) // San Francisco
 
 	// Search in Berlin
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10", nil)
+	authorize(t, req, user1)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -325,8 +610,7 @@ func TestGetNearbyUsers_NoUsersInRadius(t *testing.T) {
 }
 
 func TestGetNearbyUsers_InvalidParameters(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, _ := setupRadarTestRouter(t)
 
 	tests := []struct {
 		name  string
@@ -339,11 +623,15 @@ func TestGetNearbyUsers_InvalidParameters(t *testing.T) {
 		{"latitude out of range", "?latitude=91.0&longitude=13.4050&radius=10"},
 		{"longitude out of range", "?latitude=52.5200&longitude=181.0&radius=10"},
 		{"negative radius", "?latitude=52.5200&longitude=13.4050&radius=-10"},
+		{"radius too large", "?latitude=52.5200&longitude=13.4050&radius=20000"},
+		{"unknown mode", "?latitude=52.5200&longitude=13.4050&radius=10&mode=everyone"},
+		{"negative offset", "?latitude=52.5200&longitude=13.4050&radius=10&offset=-1"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/radar/nearby%s", tt.query), nil)
+			authorize(t, req, 1)
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
 
@@ -352,18 +640,120 @@ func TestGetNearbyUsers_InvalidParameters(t *testing.T) {
 	}
 }
 
+func TestGetNearbyUsers_Pagination(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "pager@example.com")
+	var expected []int
+	for i := 0; i < 5; i++ {
+		userID := createTestUser(t, store, fmt.Sprintf("page%d@example.com", i))
+		placeTestUser(t, store, userID, 52.5200+float64(i)*0.001, 13.4050, true)
+		expected = append(expected, userID)
+	}
+
+	type page struct {
+		Count      int                 `json:"count"`
+		Users      []models.NearbyUser `json:"users"`
+		NextOffset *int                `json:"next_offset"`
+	}
+
+	var seen []int
+	path := "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10&limit=2"
+	offset := 0
+	for pages := 0; pages < 5; pages++ {
+		w := performJSON(t, router, http.MethodGet, fmt.Sprintf("%s&offset=%d", path, offset), nil, viewer)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response page
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		for _, user := range response.Users {
+			seen = append(seen, user.UserID)
+		}
+		if response.NextOffset == nil {
+			break
+		}
+		offset = *response.NextOffset
+	}
+
+	assert.Equal(t, expected, seen, "pages walk all users closest first without repeats")
+}
+
+func TestGetNearbyUsers_NearestMode(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "knn@example.com")
+	hamburg := createTestUser(t, store, "hamburg@example.com")
+	munich := createTestUser(t, store, "munich@example.com")
+	placeTestUser(t, store, hamburg, 53.5511, 9.9937, true)
+	placeTestUser(t, store, munich, 48.1351, 11.5820, true)
+
+	// Nobody is within any small radius, but nearest mode still finds the closest
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&mode=nearest&limit=1", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Users      []models.NearbyUser `json:"users"`
+		NextOffset *int                `json:"next_offset"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Users, 1)
+	assert.Equal(t, hamburg, response.Users[0].UserID)
+	assert.NotNil(t, response.NextOffset)
+
+	// A radius still bounds nearest mode when given
+	w = performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&mode=nearest&radius=100", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Empty(t, response.Users)
+}
+
+func TestGetNearbyUsers_MaxAccuracy(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "viewer@example.com")
+	precise := createTestUser(t, store, "precise@example.com")
+	coarse := createTestUser(t, store, "coarse@example.com")
+	unknown := createTestUser(t, store, "unknown@example.com")
+
+	for userID, accuracy := range map[int]float64{precise: 10, coarse: 500} {
+		w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location", gin.H{
+			"latitude": 52.5210, "longitude": 13.4060, "accuracy_meters": accuracy,
+		}, userID)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	placeTestUser(t, store, unknown, 52.5210, 13.4060, true)
+
+	var response struct {
+		Users []models.NearbyUser `json:"users"`
+	}
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Users, 3)
+
+	// Fixes without a reported accuracy are dropped along with coarse ones
+	w = performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10&max_accuracy=50", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Users, 1)
+	assert.Equal(t, precise, response.Users[0].UserID)
+
+	w = performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10&max_accuracy=-5", nil, viewer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestGetNearbyUsers_DistanceCalculation(t *testing.T) {
-	router, db := setupRadarTestRouter(t)
-	defer db.Close()
+	router, store := setupRadarTestRouter(t)
 
-	user1 := createTestUser(t, db, "distance@example.com")
+	user1 := createTestUser(t, store, "distance@example.com")
 
 	// Add user at known location
-	db.DB.Exec("INSERT INTO user_radar (user_id, location, is_active) VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), true)",
-		user1, 13.4050, 52.5200)
+	placeTestUser(t, store, user1, 52.5200, 13.4050, true)
 
 	// Search from slightly different location
+	viewer := createTestUser(t, store, "viewer@example.com")
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/radar/nearby?latitude=52.5300&longitude=13.4150&radius=10", nil)
+	authorize(t, req, viewer)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -383,3 +773,514 @@ func TestGetNearbyUsers_DistanceCalculation(t *testing.T) {
 	assert.Greater(t, distance, 1.0)
 	assert.Less(t, distance, 2.0)
 }
+
+func TestUpdatePrivacy_DefaultsAndValidation(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	userID := createTestUser(t, store, "private@example.com")
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/privacy", nil, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var settings models.PrivacySettings
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, models.VisibilityExact, settings.Visibility)
+
+	w = performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "fuzzed"}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, models.VisibilityFuzzed, settings.Visibility)
+	assert.Equal(t, 1000, settings.FuzzMeters)
+
+	// The grid size is dropped for other visibility levels
+	w = performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "distance_only", "fuzz_meters": 500}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.PrivacySettings
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Zero(t, updated.FuzzMeters)
+
+	for _, body := range []gin.H{
+		{"visibility": "invisible"},
+		{"visibility": "fuzzed", "fuzz_meters": 10},
+		{},
+	} {
+		w = performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", body, userID)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetNearbyUsers_AppliesPrivacy(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "viewer@example.com")
+	exact := createTestUser(t, store, "exact@example.com")
+	fuzzed := createTestUser(t, store, "fuzzed@example.com")
+	distanceOnly := createTestUser(t, store, "distance@example.com")
+	hidden := createTestUser(t, store, "hidden@example.com")
+
+	for userID, visibility := range map[int]string{fuzzed: "fuzzed", distanceOnly: "distance_only", hidden: "hidden"} {
+		w := performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": visibility}, userID)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	for _, userID := range []int{exact, fuzzed, distanceOnly, hidden} {
+		placeTestUser(t, store, userID, 52.5210, 13.4060, true)
+	}
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=10", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Count int                 `json:"count"`
+		Users []models.NearbyUser `json:"users"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Count)
+
+	byID := make(map[int]models.NearbyUser)
+	for _, user := range response.Users {
+		byID[user.UserID] = user
+	}
+	assert.NotContains(t, byID, hidden)
+
+	assert.InDelta(t, 52.5210, *byID[exact].Latitude, 0.000001)
+
+	assert.Equal(t, "fuzzed", byID[fuzzed].Visibility)
+	assert.Equal(t, 1000, byID[fuzzed].FuzzMeters)
+	assert.NotEqual(t, 52.5210, *byID[fuzzed].Latitude)
+
+	assert.Equal(t, "distance_only", byID[distanceOnly].Visibility)
+	assert.Nil(t, byID[distanceOnly].Latitude)
+	assert.Nil(t, byID[distanceOnly].Longitude)
+	assert.Equal(t, 0.5, byID[distanceOnly].DistanceKm)
+}
+
+func TestGetNearbyUsers_ShrinkingRadiusDoesNotRevealDistance(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "viewer@example.com")
+	fuzzed := createTestUser(t, store, "fuzzed@example.com")
+	distanceOnly := createTestUser(t, store, "distance@example.com")
+
+	for userID, visibility := range map[int]string{fuzzed: "fuzzed", distanceOnly: "distance_only"} {
+		w := performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": visibility}, userID)
+		assert.Equal(t, http.StatusOK, w.Code)
+		placeTestUser(t, store, userID, 52.5350, 13.4300, true)
+	}
+	exactKm := geo.DistanceMeters(52.5200, 13.4050, 52.5350, 13.4300) / 1000
+
+	// Shrink the radius in 10 m steps and note the smallest one that still
+	// finds each user
+	smallest := make(map[int]float64)
+	for step := 500; step >= 1; step-- {
+		radius := float64(step) / 100
+		w := performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/nearby?latitude=52.5200&longitude=13.4050&radius=%.2f", radius), nil, viewer)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Users []models.NearbyUser `json:"users"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		for _, user := range response.Users {
+			smallest[user.UserID] = radius
+		}
+	}
+
+	// The search only narrows down the distance that is reported anyway,
+	// which is hundreds of meters off the exact one
+	cellLat, cellLon := privacy.Fuzz(52.5350, 13.4300, privacy.DefaultFuzzMeters)
+	fuzzedKm := geo.DistanceMeters(52.5200, 13.4050, cellLat, cellLon) / 1000
+	assert.InDelta(t, fuzzedKm, smallest[fuzzed], 0.01)
+	assert.Greater(t, smallest[fuzzed]-exactKm, 0.2)
+
+	assert.Equal(t, 3.0, smallest[distanceOnly])
+	assert.Greater(t, smallest[distanceOnly]-exactKm, 0.5)
+}
+
+func TestGetUsersInBoundingBox(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "map@example.com")
+	inside := createTestUser(t, store, "inside@example.com")
+	outside := createTestUser(t, store, "outside@example.com")
+	placeTestUser(t, store, viewer, 52.5200, 13.4050, true)
+	placeTestUser(t, store, inside, 52.5210, 13.4060, true)
+	placeTestUser(t, store, outside, 52.6000, 13.4050, true)
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/bbox?min_latitude=52.51&min_longitude=13.39&max_latitude=52.53&max_longitude=13.42", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Count      int                 `json:"count"`
+		Users      []models.NearbyUser `json:"users"`
+		NextOffset *int                `json:"next_offset"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Count)
+	assert.Equal(t, inside, response.Users[0].UserID)
+	assert.Nil(t, response.NextOffset)
+}
+
+func TestGetUsersInBoundingBox_InvalidParameters(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "map@example.com")
+
+	for _, query := range []string{
+		"min_latitude=52.51&min_longitude=13.39&max_latitude=52.53",
+		"min_latitude=52.53&min_longitude=13.39&max_latitude=52.51&max_longitude=13.42",
+		"min_latitude=52.51&min_longitude=13.39&max_latitude=91&max_longitude=13.42",
+		// Roughly 2000 km across
+		"min_latitude=40&min_longitude=0&max_latitude=55&max_longitude=20",
+	} {
+		w := performJSON(t, router, http.MethodGet, "/api/v1/radar/bbox?"+query, nil, viewer)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestFindUsersInPolygon(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "draw@example.com")
+	inside := createTestUser(t, store, "inside@example.com")
+	notch := createTestUser(t, store, "notch@example.com")
+	placeTestUser(t, store, inside, 52.5050, 13.4050, true)
+	placeTestUser(t, store, notch, 52.5150, 13.4150, true)
+
+	// An L shape covering a 0.02 degree square minus its north-east quarter
+	polygon := models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][]float64{{
+		{13.40, 52.50}, {13.42, 52.50}, {13.42, 52.51}, {13.41, 52.51},
+		{13.41, 52.52}, {13.40, 52.52}, {13.40, 52.50},
+	}}}
+	w := performJSON(t, router, http.MethodPost, "/api/v1/radar/polygon", polygon, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Count int                 `json:"count"`
+		Users []models.NearbyUser `json:"users"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Count)
+	assert.Equal(t, inside, response.Users[0].UserID)
+
+	open := models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][]float64{{
+		{13.40, 52.50}, {13.42, 52.50}, {13.42, 52.52},
+	}}}
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/polygon", open, viewer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performJSON(t, router, http.MethodPost, "/api/v1/radar/polygon?offset=5000", polygon, viewer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetUsersInBoundingBox_RespectsPrivacy(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "map@example.com")
+	distanceOnly := createTestUser(t, store, "distance@example.com")
+	fuzzed := createTestUser(t, store, "fuzzed@example.com")
+
+	w := performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "distance_only"}, distanceOnly)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "fuzzed", "fuzz_meters": 5000}, fuzzed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	placeTestUser(t, store, distanceOnly, 52.5210, 13.4060, true)
+	placeTestUser(t, store, fuzzed, 52.5210, 13.4060, true)
+
+	// A tiny viewport around the exact position must not reveal either user
+	w = performJSON(t, router, http.MethodGet, "/api/v1/radar/bbox?min_latitude=52.5209&min_longitude=13.4059&max_latitude=52.5211&max_longitude=13.4061", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Count int `json:"count"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Zero(t, response.Count)
+
+	// A tiny viewport around the fuzzed position finds the fuzzed user,
+	// although the exact position is far outside it
+	latitude, longitude := privacy.Fuzz(52.5210, 13.4060, 5000)
+	assert.Greater(t, geo.DistanceMeters(52.5210, 13.4060, latitude, longitude), 500.0)
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/bbox?min_latitude=%f&min_longitude=%f&max_latitude=%f&max_longitude=%f",
+		latitude-0.0001, longitude-0.0001, latitude+0.0001, longitude+0.0001), nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var found struct {
+		Users []models.NearbyUser `json:"users"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &found))
+	assert.Len(t, found.Users, 1)
+	assert.Equal(t, fuzzed, found.Users[0].UserID)
+}
+
+func TestGetUsersInBoundingBox_FullPages(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "map@example.com")
+	for i := 0; i < 3; i++ {
+		userID := createTestUser(t, store, fmt.Sprintf("distance%d@example.com", i))
+		w := performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "distance_only"}, userID)
+		assert.Equal(t, http.StatusOK, w.Code)
+		placeTestUser(t, store, userID, 52.5200, 13.4050, true)
+	}
+	exact := createTestUser(t, store, "exact@example.com")
+	placeTestUser(t, store, exact, 52.5210, 13.4060, true)
+
+	// Distance-only users closer to the center do not use up the page
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/bbox?min_latitude=52.51&min_longitude=13.39&max_latitude=52.53&max_longitude=13.42&limit=1", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Users      []models.NearbyUser `json:"users"`
+		NextOffset *int                `json:"next_offset"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Users, 1)
+	assert.Equal(t, exact, response.Users[0].UserID)
+	assert.Nil(t, response.NextOffset)
+}
+
+func TestGetClusters(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "map@example.com")
+	mitte := createTestUser(t, store, "mitte@example.com")
+	alexanderplatz := createTestUser(t, store, "alex@example.com")
+	potsdam := createTestUser(t, store, "potsdam@example.com")
+	distanceOnly := createTestUser(t, store, "distance@example.com")
+
+	w := performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "distance_only"}, distanceOnly)
+	assert.Equal(t, http.StatusOK, w.Code)
+	placeTestUser(t, store, viewer, 52.5200, 13.4050, true)
+	placeTestUser(t, store, mitte, 52.5200, 13.4050, true)
+	placeTestUser(t, store, alexanderplatz, 52.5210, 13.4060, true)
+	placeTestUser(t, store, potsdam, 52.3906, 13.0645, true)
+	placeTestUser(t, store, distanceOnly, 52.5200, 13.4050, true)
+
+	w = performJSON(t, router, http.MethodGet, "/api/v1/radar/clusters?min_latitude=52.3&min_longitude=13.0&max_latitude=52.6&max_longitude=13.6&zoom=10", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Count    int                   `json:"count"`
+		Clusters []models.RadarCluster `json:"clusters"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Count)
+
+	// The viewer and the distance-only user are not counted
+	berlin := response.Clusters[0]
+	assert.Equal(t, 2, berlin.Count)
+	assert.InDelta(t, 52.5205, berlin.Latitude, 0.000001)
+	assert.InDelta(t, 13.4055, berlin.Longitude, 0.000001)
+	assert.Equal(t, models.ClusterBounds{MinLatitude: 52.5200, MinLongitude: 13.4050, MaxLatitude: 52.5210, MaxLongitude: 13.4060}, berlin.Bounds)
+	assert.Equal(t, 1, response.Clusters[1].Count)
+
+	// Zoomed out far enough, both fall into one cell
+	w = performJSON(t, router, http.MethodGet, "/api/v1/radar/clusters?min_latitude=52.3&min_longitude=13.0&max_latitude=52.6&max_longitude=13.6&zoom=5", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Count)
+	assert.Equal(t, 3, response.Clusters[0].Count)
+}
+
+func TestGetClusters_FuzzedPositions(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "map@example.com")
+	fuzzed := createTestUser(t, store, "fuzzed@example.com")
+
+	w := performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "fuzzed", "fuzz_meters": 5000}, fuzzed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	placeTestUser(t, store, fuzzed, 52.5210, 13.4060, true)
+
+	w = performJSON(t, router, http.MethodGet, "/api/v1/radar/clusters?min_latitude=52.50&min_longitude=13.38&max_latitude=52.55&max_longitude=13.44&zoom=16", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Clusters []models.RadarCluster `json:"clusters"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Clusters, 1)
+
+	// Even a single-user cluster at a high zoom level only shows the fuzzed position
+	latitude, longitude := privacy.Fuzz(52.5210, 13.4060, 5000)
+	assert.Equal(t, latitude, response.Clusters[0].Latitude)
+	assert.Equal(t, longitude, response.Clusters[0].Longitude)
+	assert.Equal(t, latitude, response.Clusters[0].Bounds.MinLatitude)
+
+	// A box around the fuzzed position alone still counts the user
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/clusters?min_latitude=%f&min_longitude=%f&max_latitude=%f&max_longitude=%f&zoom=16",
+		latitude-0.001, longitude-0.001, latitude+0.001, longitude+0.001), nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Clusters, 1)
+}
+
+func TestGetClusters_InvalidParameters(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	viewer := createTestUser(t, store, "map@example.com")
+
+	for _, query := range []string{
+		"min_latitude=52.3&min_longitude=13.0&max_latitude=52.6&max_longitude=13.6",
+		"min_latitude=52.3&min_longitude=13.0&max_latitude=52.6&max_longitude=13.6&zoom=23",
+		"min_latitude=52.6&min_longitude=13.0&max_latitude=52.3&max_longitude=13.6&zoom=10",
+		// The whole world at street level
+		"min_latitude=-90&min_longitude=-180&max_latitude=90&max_longitude=180&zoom=16",
+	} {
+		w := performJSON(t, router, http.MethodGet, "/api/v1/radar/clusters?"+query, nil, viewer)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// The whole world is fine when zoomed out
+	w := performJSON(t, router, http.MethodGet, "/api/v1/radar/clusters?min_latitude=-90&min_longitude=-180&max_latitude=90&max_longitude=180&zoom=0", nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetLocationHistory_ReturnsTrail(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	userID := createTestUser(t, store, "trail@example.com")
+	placeTestUser(t, store, userID, 52.5200, 13.4050, true)
+	placeTestUser(t, store, userID, 52.5210, 13.4060, true)
+	placeTestUser(t, store, userID, 52.5220, 13.4070, false)
+
+	w := performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history", userID), nil, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Count  int                    `json:"count"`
+		Points []models.LocationPoint `json:"points"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Count)
+	assert.InDelta(t, 52.5200, response.Points[0].Latitude, 0.0001)
+	assert.False(t, response.Points[2].IsActive)
+
+	// Limit keeps the most recent points
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history?limit=2", userID), nil, userID)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Count)
+	assert.InDelta(t, 52.5210, response.Points[0].Latitude, 0.0001)
+
+	// Time range excludes everything in the past
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history?to=2000-01-01T00:00:00Z", userID), nil, userID)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 0, response.Count)
+}
+
+func TestGetLocationHistory_GeoJSON(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	userID := createTestUser(t, store, "geojson@example.com")
+	placeTestUser(t, store, userID, 52.5200, 13.4050, true)
+
+	w := performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history?format=geojson", userID), nil, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+
+	var feature models.GeoJSONFeature
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &feature))
+	assert.Nil(t, feature.Geometry, "a single point cannot form a LineString")
+
+	placeTestUser(t, store, userID, 52.5300, 13.4150, true)
+
+	w = performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history?format=geojson", userID), nil, userID)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &feature))
+	assert.Equal(t, "Feature", feature.Type)
+	assert.Equal(t, "LineString", feature.Geometry.Type)
+
+	coordinates := feature.Geometry.Coordinates.([]interface{})
+	assert.Len(t, coordinates, 2)
+	first := coordinates[0].([]interface{})
+	assert.InDelta(t, 13.4050, first[0].(float64), 0.0001, "GeoJSON positions are [lon, lat]")
+}
+
+func TestGetLocationHistory_Forbidden(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+
+	me := createTestUser(t, store, "me@example.com")
+	other := createTestUser(t, store, "other@example.com")
+
+	w := performJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/radar/users/%d/history", other), nil, me)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// readSSEvent reads the next named event from an SSE stream, skipping
+// keep-alive comments
+func readSSEvent(t *testing.T, scanner *bufio.Scanner) (string, models.RadarEvent) {
+	var name string
+	var event models.RadarEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event))
+		case line == "" && name != "":
+			return name, event
+		}
+	}
+	t.Fatalf("stream ended before next event: %v", scanner.Err())
+	return "", event
+}
+
+func TestStreamNearbyUsers_EnterMoveLeave(t *testing.T) {
+	router, store := setupRadarTestRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	watcher := createTestUser(t, store, "watcher@example.com")
+	resident := createTestUser(t, store, "resident@example.com")
+	mover := createTestUser(t, store, "mover@example.com")
+	placeTestUser(t, store, resident, 52.5200, 13.4050, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/radar/stream?latitude=52.5200&longitude=13.4050&radius=10", nil)
+	authorize(t, req, watcher)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	scanner := bufio.NewScanner(resp.Body)
+
+	// Snapshot of users already inside
+	name, event := readSSEvent(t, scanner)
+	assert.Equal(t, "enter", name)
+	assert.Equal(t, resident, event.UserID)
+
+	moveTo := func(latitude, longitude float64) {
+		w := performJSON(t, router, http.MethodPost, "/api/v1/radar/location", models.UpdateLocationRequest{
+			Latitude:  latitude,
+			Longitude: longitude,
+		}, mover)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	moveTo(48.1351, 11.5820) // Munich, outside the area: no event
+	moveTo(52.5210, 13.4060)
+	name, event = readSSEvent(t, scanner)
+	assert.Equal(t, "enter", name)
+	assert.Equal(t, mover, event.UserID)
+
+	moveTo(52.5300, 13.4150)
+	name, event = readSSEvent(t, scanner)
+	assert.Equal(t, "move", name)
+	assert.InDelta(t, 52.5300, *event.Latitude, 0.0001)
+
+	moveTo(48.1351, 11.5820)
+	name, event = readSSEvent(t, scanner)
+	assert.Equal(t, "leave", name)
+	assert.Equal(t, mover, event.UserID)
+	assert.Nil(t, event.Latitude)
+
+	// Hiding takes effect on open streams without another location update
+	w := performJSON(t, router, http.MethodPut, "/api/v1/radar/privacy", gin.H{"visibility": "hidden"}, resident)
+	assert.Equal(t, http.StatusOK, w.Code)
+	name, event = readSSEvent(t, scanner)
+	assert.Equal(t, "leave", name)
+	assert.Equal(t, resident, event.UserID)
+}
@@ -1,110 +1,317 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
-	"api-backend/internal/database"
+	"api-backend/internal/auth"
+	"api-backend/internal/middleware"
 	"api-backend/internal/models"
+	"api-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	db *database.Database
+	users repository.UserRepository
 }
 
-func NewUserHandler(db *database.Database) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(users repository.UserRepository) *UserHandler {
+	return &UserHandler{users: users}
 }
 
 func (h *UserHandler) Create(c *gin.Context) {
 	var req models.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var user models.User
-	err := h.db.DB.QueryRow(
-		"INSERT INTO users (email) VALUES ($1) RETURNING id, email, created_at, updated_at",
-		req.Email,
-	).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		internalError(c, "failed to create user", err)
+		return
+	}
+
+	user, err := h.users.Create(c.Request.Context(), req.Email, passwordHash)
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		respondError(c, http.StatusConflict, "email already registered")
+		return
+	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		internalError(c, "failed to create user", err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, user)
 }
 
+// GetByID returns the full account, email included, to the user themselves
+// and to admins. Everyone else gets a 403 and reads the public profile from
+// GetProfile instead, so the response always has the same shape.
 func (h *UserHandler) GetByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	user, err := h.users.GetByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "user not found")
 		return
 	}
 
-	var user models.User
-	err = h.db.DB.QueryRow(
-		"SELECT id, email, created_at, updated_at FROM users WHERE id = $1",
-		id,
-	).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		internalError(c, "failed to fetch user", err)
+		return
+	}
 
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	if currentUserID, _ := middleware.UserID(c); currentUserID != id && !middleware.IsAdmin(c) {
+		respondError(c, http.StatusForbidden, "cannot read another user's account; use /api/v1/users/"+strconv.Itoa(id)+"/profile")
 		return
 	}
 
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// Update applies a partial update to the authenticated user's account. When
+// the client sends If-Match with the ETag from a previous read, the update is
+// rejected with 412 if the account has changed in the meantime.
+func (h *UserHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user"})
+		respondError(c, http.StatusBadRequest, "invalid user id")
 		return
 	}
 
+	// Users may only update their own account
+	if currentUserID, ok := middleware.UserID(c); !ok || currentUserID != id {
+		respondError(c, http.StatusForbidden, "cannot update another user")
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Email == nil {
+		respondError(c, http.StatusBadRequest, "no fields to update")
+		return
+	}
+
+	current, err := h.users.GetByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to fetch user", err)
+		return
+	}
+
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && !etagMatches(ifMatch, userETag(current)) {
+		respondError(c, http.StatusPreconditionFailed, "user was modified by another request")
+		return
+	}
+
+	// Guard the write with the version just read so a concurrent update
+	// between the read and the write is still detected
+	user, err := h.users.Update(c.Request.Context(), id, repository.UserUpdate{
+		Email: req.Email,
+	}, &current.UpdatedAt)
+
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		respondError(c, http.StatusPreconditionFailed, "user was modified by another request")
+		return
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		respondError(c, http.StatusConflict, "email already registered")
+		return
+	case errors.Is(err, repository.ErrNotFound):
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	case err != nil:
+		internalError(c, "failed to update user", err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
 	c.JSON(http.StatusOK, user)
 }
 
+// List returns a page of users, newest first by default. The response carries
+// a next_cursor token to pass back as ?cursor= for the following page. Since
+// it includes email addresses, only admins may list users.
 func (h *UserHandler) List(c *gin.Context) {
-	rows, err := h.db.DB.Query("SELECT id, email, created_at, updated_at FROM users ORDER BY created_at DESC")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch users"})
+	if !middleware.IsAdmin(c) {
+		respondError(c, http.StatusForbidden, "admin access required")
+		return
+	}
+
+	var req models.ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer rows.Close()
 
-	users := []models.User{}
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to scan user"})
+	filter := repository.UserFilter{
+		EmailContains: req.Email,
+		EmailPrefix:   req.EmailPrefix,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Ascending:     req.Order == "asc",
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeUserCursor(req.Cursor, filter.Ascending)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		users = append(users, user)
+		filter.After = cursor
 	}
 
-	c.JSON(http.StatusOK, users)
+	// Fetch one extra row to learn whether another page exists
+	limit := pageSize(req.Limit)
+	filter.Limit = limit + 1
+
+	users, err := h.users.List(c.Request.Context(), filter)
+	if err != nil {
+		internalError(c, "failed to fetch users", err)
+		return
+	}
+
+	var nextCursor *string
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[len(users)-1]
+		token := encodeUserCursor(repository.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}, filter.Ascending)
+		nextCursor = &token
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":       len(users),
+		"users":       users,
+		"next_cursor": nextCursor,
+	})
 }
 
 func (h *UserHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		respondError(c, http.StatusBadRequest, "invalid user id")
 		return
 	}
 
-	result, err := h.db.DB.Exec("DELETE FROM users WHERE id = $1", id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete user"})
+	// Users may only delete their own account
+	if currentUserID, ok := middleware.UserID(c); !ok || currentUserID != id {
+		respondError(c, http.StatusForbidden, "cannot delete another user")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	err = h.users.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to delete user", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
 }
+
+// GetProfile returns a user's public profile, which unlike the full user
+// record does not include the email address
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	user, err := h.users.GetByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to fetch profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PublicProfile{UserID: user.ID, Profile: user.Profile})
+}
+
+// UpdateProfile replaces the authenticated user's public profile. Like
+// Update, it honours If-Match and answers 412 when the user has changed since
+// the client's read.
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	// Users may only update their own profile
+	if currentUserID, ok := middleware.UserID(c); !ok || currentUserID != id {
+		respondError(c, http.StatusForbidden, "cannot update another user's profile")
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	current, err := h.users.GetByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err != nil {
+		internalError(c, "failed to fetch user", err)
+		return
+	}
+
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && !etagMatches(ifMatch, userETag(current)) {
+		respondError(c, http.StatusPreconditionFailed, "user was modified by another request")
+		return
+	}
+
+	user, err := h.users.Update(c.Request.Context(), id, repository.UserUpdate{
+		Profile: &models.Profile{
+			DisplayName: strings.TrimSpace(req.DisplayName),
+			AvatarURL:   req.AvatarURL,
+			Bio:         strings.TrimSpace(req.Bio),
+		},
+	}, &current.UpdatedAt)
+
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		respondError(c, http.StatusPreconditionFailed, "user was modified by another request")
+		return
+	case errors.Is(err, repository.ErrNotFound):
+		respondError(c, http.StatusNotFound, "user not found")
+		return
+	case err != nil:
+		internalError(c, "failed to update profile", err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, models.PublicProfile{UserID: user.ID, Profile: user.Profile})
+}
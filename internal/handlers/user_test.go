@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"api-backend/internal/middleware"
+	"api-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAdminID is an admin in the user test router; no account needs to exist
+// for it, so it does not show up in listings
+const testAdminID = 1000000
+
+func setupUserTestRouter(t *testing.T) (*gin.Engine, *testStore) {
+	gin.SetMode(gin.TestMode)
+
+	store := newTestStore()
+
+	router := gin.New()
+	router.Use(middleware.Admins([]int{testAdminID}))
+	userHandler := NewUserHandler(store.users)
+	authHandler := NewAuthHandler(store.users, testTokens)
+	requireAuth := middleware.Auth(testTokens)
+
+	api := router.Group("/api/v1")
+	{
+		api.POST("/auth/login", authHandler.Login)
+		api.POST("/auth/refresh", authHandler.Refresh)
+
+		api.POST("/users", userHandler.Create)
+		api.GET("/users", requireAuth, userHandler.List)
+		api.GET("/users/:id", requireAuth, userHandler.GetByID)
+		api.PATCH("/users/:id", requireAuth, userHandler.Update)
+		api.DELETE("/users/:id", requireAuth, userHandler.Delete)
+		api.GET("/users/:id/profile", requireAuth, userHandler.GetProfile)
+		api.PUT("/users/:id/profile", requireAuth, userHandler.UpdateProfile)
+	}
+
+	return router, store
+}
+
+func TestCreateUser_Success(t *testing.T) {
+	router, _ := setupUserTestRouter(t)
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/users", models.CreateUserRequest{
+		Email:    "new@example.com",
+		Password: "password123",
+	}, 0)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var user models.User
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.NotZero(t, user.ID)
+	assert.NotContains(t, w.Body.String(), "password")
+}
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	createTestUser(t, store, "taken@example.com")
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/users", models.CreateUserRequest{
+		Email:    "taken@example.com",
+		Password: "password123",
+	}, 0)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateUser_InvalidBody(t *testing.T) {
+	router, _ := setupUserTestRouter(t)
+
+	tests := []struct {
+		name string
+		body models.CreateUserRequest
+	}{
+		{"invalid email", models.CreateUserRequest{Email: "not-an-email", Password: "password123"}},
+		{"short password", models.CreateUserRequest{Email: "a@example.com", Password: "short"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performJSON(t, router, http.MethodPost, "/api/v1/users", tt.body, 0)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestGetUser_NotFound(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	userID := createTestUser(t, store, "me@example.com")
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/users/99999", nil, userID)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetUser_OnlySelfOrAdmin(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+	other := createTestUser(t, store, "other@example.com")
+
+	// Others are pointed to the public profile instead
+	w := performJSON(t, router, http.MethodGet, "/api/v1/users/"+strconv.Itoa(other), nil, me)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NotContains(t, w.Body.String(), "other@example.com")
+	assert.Contains(t, w.Body.String(), "/api/v1/users/"+strconv.Itoa(other)+"/profile")
+
+	for _, caller := range []int{other, testAdminID} {
+		w = performJSON(t, router, http.MethodGet, "/api/v1/users/"+strconv.Itoa(other), nil, caller)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var user models.User
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+		assert.Equal(t, "other@example.com", user.Email)
+	}
+}
+
+func TestListUsers_RequiresAdmin(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/users", nil, 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performJSON(t, router, http.MethodGet, "/api/v1/users", nil, me)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NotContains(t, w.Body.String(), "me@example.com")
+}
+
+func TestDeleteUser_OnlySelf(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+	other := createTestUser(t, store, "other@example.com")
+
+	w := performJSON(t, router, http.MethodDelete, "/api/v1/users/"+strconv.Itoa(other), nil, me)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performJSON(t, router, http.MethodDelete, "/api/v1/users/"+strconv.Itoa(me), nil, me)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performJSON(t, router, http.MethodGet, "/api/v1/users/"+strconv.Itoa(me), nil, other)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	router, _ := setupUserTestRouter(t)
+
+	w := performJSON(t, router, http.MethodPost, "/api/v1/users", models.CreateUserRequest{
+		Email:    "login@example.com",
+		Password: "password123",
+	}, 0)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performJSON(t, router, http.MethodPost, "/api/v1/auth/login", models.LoginRequest{
+		Email:    "login@example.com",
+		Password: "wrong-password",
+	}, 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performJSON(t, router, http.MethodPost, "/api/v1/auth/login", models.LoginRequest{
+		Email:    "login@example.com",
+		Password: "password123",
+	}, 0)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tokens map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	refreshToken := tokens["refresh_token"].(string)
+
+	w = performJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", models.RefreshRequest{
+		RefreshToken: refreshToken,
+	}, 0)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", models.RefreshRequest{
+		RefreshToken: tokens["access_token"].(string),
+	}, 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func listUsers(t *testing.T, router *gin.Engine, query string, userID int) (int, []models.User, *string) {
+	w := performJSON(t, router, http.MethodGet, "/api/v1/users"+query, nil, userID)
+	if w.Code != http.StatusOK {
+		return w.Code, nil, nil
+	}
+
+	var response struct {
+		Count      int           `json:"count"`
+		Users      []models.User `json:"users"`
+		NextCursor *string       `json:"next_cursor"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, len(response.Users), response.Count)
+	return w.Code, response.Users, response.NextCursor
+}
+
+func TestListUsers_CursorPagination(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+
+	var ids []int
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		ids = append(ids, createTestUser(t, store, email))
+	}
+
+	var seen []int
+	query := "?limit=2"
+	for pages := 0; pages < 5; pages++ {
+		code, users, next := listUsers(t, router, query, testAdminID)
+		assert.Equal(t, http.StatusOK, code)
+		for _, u := range users {
+			seen = append(seen, u.ID)
+		}
+		if next == nil {
+			break
+		}
+		query = "?limit=2&cursor=" + *next
+	}
+
+	// Newest first, every user exactly once
+	assert.Equal(t, []int{ids[4], ids[3], ids[2], ids[1], ids[0]}, seen)
+
+	code, users, next := listUsers(t, router, "?order=asc&limit=3", testAdminID)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ids[0], users[0].ID)
+	assert.NotNil(t, next)
+
+	// A cursor from one ordering is rejected by the other
+	code, _, _ = listUsers(t, router, "?order=desc&cursor="+*next, testAdminID)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestListUsers_Filters(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+
+	createTestUser(t, store, "alice@example.com")
+	createTestUser(t, store, "bob@example.com")
+	createTestUser(t, store, "alicia@test.org")
+
+	_, users, _ := listUsers(t, router, "?email_prefix=ALI", testAdminID)
+	assert.Len(t, users, 2)
+
+	_, users, _ = listUsers(t, router, "?email=example", testAdminID)
+	assert.Len(t, users, 2)
+
+	_, users, _ = listUsers(t, router, "?email=%25", testAdminID)
+	assert.Len(t, users, 0)
+
+	_, users, _ = listUsers(t, router, "?created_before=2000-01-01T00:00:00Z", testAdminID)
+	assert.Len(t, users, 0)
+
+	_, users, _ = listUsers(t, router, "?created_after=2000-01-01T00:00:00Z", testAdminID)
+	assert.Len(t, users, 3)
+}
+
+func TestListUsers_InvalidParameters(t *testing.T) {
+	router, _ := setupUserTestRouter(t)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"garbage cursor", "?cursor=not-a-cursor"},
+		{"negative limit", "?limit=-1"},
+		{"unknown order", "?order=sideways"},
+		{"invalid created_after", "?created_after=yesterday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := listUsers(t, router, tt.query, testAdminID)
+			assert.Equal(t, http.StatusBadRequest, code)
+		})
+	}
+}
+
+func TestListUsers_LimitIsCapped(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+
+	for i := 0; i < maxPageSize+5; i++ {
+		createTestUser(t, store, "user"+strconv.Itoa(i)+"@example.com")
+	}
+
+	_, users, next := listUsers(t, router, "?limit=1000", testAdminID)
+	assert.Len(t, users, maxPageSize)
+	assert.NotNil(t, next)
+}
+
+func patchUser(t *testing.T, router *gin.Engine, id int, body interface{}, ifMatch string, userID int) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+strconv.Itoa(id), bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	authorize(t, req, userID)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateUser_ChangesEmailAndETag(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	me := createTestUser(t, store, "old@example.com")
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/users/"+strconv.Itoa(me), nil, me)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = patchUser(t, router, me, gin.H{"email": "new@example.com"}, etag, me)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	var user models.User
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(t, "new@example.com", user.Email)
+}
+
+func TestUpdateUser_StaleIfMatch(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/users/"+strconv.Itoa(me), nil, me)
+	etag := w.Header().Get("ETag")
+
+	// First device wins
+	w = patchUser(t, router, me, gin.H{"email": "first@example.com"}, etag, me)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Second device still holds the old ETag
+	w = patchUser(t, router, me, gin.H{"email": "second@example.com"}, etag, me)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	// Wildcard and missing If-Match are unconditional
+	w = patchUser(t, router, me, gin.H{"email": "third@example.com"}, "*", me)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = patchUser(t, router, me, gin.H{"email": "fourth@example.com"}, "", me)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUpdateUser_Errors(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+	other := createTestUser(t, store, "other@example.com")
+
+	w := patchUser(t, router, other, gin.H{"email": "x@example.com"}, "", me)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = patchUser(t, router, me, gin.H{"email": "other@example.com"}, "", me)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = patchUser(t, router, me, gin.H{"email": "not-an-email"}, "", me)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = patchUser(t, router, me, gin.H{}, "", me)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateProfile_Success(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+
+	userID := createTestUser(t, store, "profile@example.com")
+	viewer := createTestUser(t, store, "viewer@example.com")
+	path := "/api/v1/users/" + strconv.Itoa(userID) + "/profile"
+
+	w := performJSON(t, router, http.MethodPut, path, models.UpdateProfileRequest{
+		DisplayName: "  Ada  ",
+		AvatarURL:   "https://example.com/ada.png",
+		Bio:         "Climbing and coffee",
+	}, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Other users see the profile but not the email address
+	w = performJSON(t, router, http.MethodGet, path, nil, viewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "profile@example.com")
+
+	var profile models.PublicProfile
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+	assert.Equal(t, userID, profile.UserID)
+	assert.Equal(t, "Ada", profile.DisplayName)
+	assert.Equal(t, "https://example.com/ada.png", profile.AvatarURL)
+	assert.Equal(t, "Climbing and coffee", profile.Bio)
+}
+
+func TestUpdateProfile_StaleIfMatch(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+	me := createTestUser(t, store, "me@example.com")
+	path := "/api/v1/users/" + strconv.Itoa(me) + "/profile"
+
+	w := performJSON(t, router, http.MethodGet, "/api/v1/users/"+strconv.Itoa(me), nil, me)
+	etag := w.Header().Get("ETag")
+
+	putProfile := func(name, ifMatch string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(models.UpdateProfileRequest{DisplayName: name})
+		req, _ := http.NewRequest(http.MethodPut, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		authorize(t, req, me)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// First device wins and gets the new ETag
+	w = putProfile("First", etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// Second device still holds the old ETag
+	w = putProfile("Second", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = performJSON(t, router, http.MethodGet, path, nil, me)
+	assert.Contains(t, w.Body.String(), "First")
+}
+
+func TestUpdateProfile_Errors(t *testing.T) {
+	router, store := setupUserTestRouter(t)
+
+	userID := createTestUser(t, store, "owner@example.com")
+	other := createTestUser(t, store, "other@example.com")
+	path := "/api/v1/users/" + strconv.Itoa(userID) + "/profile"
+
+	w := performJSON(t, router, http.MethodPut, path, models.UpdateProfileRequest{DisplayName: "Mallory"}, other)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performJSON(t, router, http.MethodPut, path, models.UpdateProfileRequest{AvatarURL: "javascript:alert(1)"}, userID)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performJSON(t, router, http.MethodPut, path, models.UpdateProfileRequest{DisplayName: strings.Repeat("a", 51)}, userID)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performJSON(t, router, http.MethodGet, "/api/v1/users/999/profile", nil, userID)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric served on /metrics. It is separate from the
+// Prometheus default registry so only metrics registered here are exposed.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by method and route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	HTTPRequestsInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being handled.",
+	})
+
+	// LocationUpdates counts stored fixes; rate() over it gives updates per
+	// second
+	LocationUpdates = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "radar_location_updates_total",
+		Help: "Location fixes stored, by source (single or batch).",
+	}, []string{"source"})
+
+	LocationFlags = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "radar_location_flags_total",
+		Help: "Location updates flagged as implausible, by action taken (rejected or flagged).",
+	}, []string{"action"})
+)
+
+// collectTimeout bounds the queries run while serving a scrape
+const collectTimeout = 5 * time.Second
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db as go_sql_*
+// metrics
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ActiveCounter counts the users currently on the radar
+type ActiveCounter interface {
+	CountActive(ctx context.Context) (int, error)
+}
+
+// RegisterRadar exposes the number of active radar users, counted on every
+// scrape
+func RegisterRadar(counter ActiveCounter) {
+	Registry.MustRegister(&radarCollector{counter: counter})
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+var activeUsersDesc = prometheus.NewDesc(
+	"radar_active_users",
+	"Users with a current, non-stale radar position.",
+	nil, nil,
+)
+
+type radarCollector struct {
+	counter ActiveCounter
+}
+
+func (rc *radarCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeUsersDesc
+}
+
+// Collect leaves the gauge out of the scrape when counting fails, so a
+// failed query does not read as zero users
+func (rc *radarCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	count, err := rc.counter.CountActive(ctx)
+	if err != nil {
+		slog.Error("failed to count active radar users", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(activeUsersDesc, prometheus.GaugeValue, float64(count))
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"api-backend/internal/models"
+	"api-backend/internal/repository/memory"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRadarCollector_CountsActiveUsers(t *testing.T) {
+	users := memory.NewUserRepository()
+	radar := memory.NewRadarRepository(users, time.Hour)
+	ctx := context.Background()
+
+	for i, active := range []bool{true, true, false} {
+		user, err := users.Create(ctx, fmt.Sprintf("user%d@example.com", i), "hash")
+		require.NoError(t, err)
+		_, err = radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 52.52, Longitude: 13.405, IsActive: active}, nil)
+		require.NoError(t, err)
+	}
+
+	expected := `
+# HELP radar_active_users Users with a current, non-stale radar position.
+# TYPE radar_active_users gauge
+radar_active_users 2
+`
+	err := testutil.CollectAndCompare(&radarCollector{counter: radar}, strings.NewReader(expected))
+	assert.NoError(t, err)
+}
+
+type failingCounter struct{}
+
+func (failingCounter) CountActive(ctx context.Context) (int, error) {
+	return 0, errors.New("database is down")
+}
+
+func TestRadarCollector_OmitsGaugeOnError(t *testing.T) {
+	assert.Equal(t, 0, testutil.CollectAndCount(&radarCollector{counter: failingCounter{}}))
+}
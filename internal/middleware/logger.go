@@ -1,35 +1,51 @@
 package middleware
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"time"
 
+	"api-backend/internal/logging"
+
 	"github.com/gin-gonic/gin"
 )
 
+// Logger writes one line per handled request with the request described in
+// Cloud Logging's httpRequest format. Server errors are logged as errors.
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
 
 		c.Next()
 
 		latency := time.Since(start)
-		statusCode := c.Writer.Status()
-		method := c.Request.Method
-		clientIP := c.ClientIP()
+		status := c.Writer.Status()
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
 
-		if raw != "" {
-			path = path + "?" + raw
+		attrs := []any{
+			slog.Group("httpRequest",
+				slog.String("requestMethod", c.Request.Method),
+				slog.String("requestUrl", c.Request.URL.RequestURI()),
+				slog.Int("status", status),
+				slog.Int("responseSize", max(c.Writer.Size(), 0)),
+				slog.String("userAgent", c.Request.UserAgent()),
+				slog.String("remoteIp", c.ClientIP()),
+				slog.String("latency", fmt.Sprintf("%.6fs", latency.Seconds())),
+			),
+		}
+		if userID, ok := UserID(c); ok {
+			attrs = append(attrs, slog.Int("user_id", userID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
 		}
 
-		log.Printf("[%s] %d | %v | %s | %s",
-			method,
-			statusCode,
-			latency,
-			clientIP,
-			path,
-		)
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).Log(ctx, level, fmt.Sprintf("%s %s %d", c.Request.Method, c.Request.URL.Path, status), attrs...)
 	}
 }
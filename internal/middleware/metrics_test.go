@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-backend/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_LabelsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	matched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/users/:id", "200")
+	unmatched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	matchedBefore, unmatchedBefore := testutil.ToFloat64(matched), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/users/1", "/users/2", "/nope/1", "/nope/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, matchedBefore+2, testutil.ToFloat64(matched))
+	assert.Equal(t, unmatchedBefore+2, testutil.ToFloat64(unmatched))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.HTTPRequestsInFlight))
+}
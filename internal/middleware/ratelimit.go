@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"api-backend/internal/logging"
+	"api-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit takes one request from the caller's bucket for policy and
+// rejects the request with 429 once it is empty. Callers are keyed by the
+// authenticated user when Auth ran first and by client IP otherwise. The
+// request is let through if the store fails, so an outage of the store does
+// not take the API down with it.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := policy.Name + ":ip:" + c.ClientIP()
+		if userID, ok := UserID(c); ok {
+			key = policy.Name + ":user:" + strconv.Itoa(userID)
+		}
+
+		result, err := store.Take(c.Request.Context(), key, policy)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("rate limit check failed", "key", key, "error", err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ratelimit.Seconds(result.ResetAfter)))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ratelimit.Seconds(policy.Period)))
+
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ratelimit.Seconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorBody(c, "rate limit exceeded"))
+			return
+		}
+
+		c.Next()
+	}
+}
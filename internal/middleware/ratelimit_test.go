@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRateLimitRouter(userID int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if userID != 0 {
+		router.Use(func(c *gin.Context) { c.Set(userIDKey, userID) })
+	}
+	router.Use(RateLimit(ratelimit.NewMemoryStore(), ratelimit.Policy{Name: "test", Limit: 2, Period: time.Minute}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func get(router *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	return getForwarded(router, remoteAddr, "")
+}
+
+func getForwarded(router *gin.Engine, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_Headers(t *testing.T) {
+	router := setupRateLimitRouter(0)
+
+	w := get(router, "192.0.2.1:1234")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = get(router, "192.0.2.1:1234")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = get(router, "192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+
+	// Another client IP is not affected
+	w = get(router, "192.0.2.2:1234")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRateLimit_KeysByUser(t *testing.T) {
+	router := setupRateLimitRouter(42)
+
+	get(router, "192.0.2.1:1234")
+	get(router, "192.0.2.2:1234")
+
+	// The same user is limited from any IP
+	w := get(router, "192.0.2.3:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestRateLimit_IgnoresSpoofedForwardedFor(t *testing.T) {
+	router := setupRateLimitRouter(0)
+	// Trust no proxy, as the server does when TRUSTED_PROXIES is empty
+	assert.NoError(t, router.SetTrustedProxies(nil))
+
+	getForwarded(router, "192.0.2.1:1234", "198.51.100.1")
+	getForwarded(router, "192.0.2.1:1234", "198.51.100.2")
+
+	w := getForwarded(router, "192.0.2.1:1234", "198.51.100.3")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestRateLimit_TrustedProxyForwardsClientIP(t *testing.T) {
+	router := setupRateLimitRouter(0)
+	assert.NoError(t, router.SetTrustedProxies([]string{"10.0.0.0/8"}))
+
+	getForwarded(router, "10.0.0.1:1234", "198.51.100.1")
+	getForwarded(router, "10.0.0.1:1234", "198.51.100.1")
+
+	// A spoofed entry before the one the proxy appended is ignored
+	w := getForwarded(router, "10.0.0.1:1234", "203.0.113.9, 198.51.100.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	w = getForwarded(router, "10.0.0.1:1234", "198.51.100.2")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
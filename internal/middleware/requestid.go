@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"api-backend/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDKey       = "request_id"
+	maxRequestIDLength = 128
+)
+
+var (
+	// X-Cloud-Trace-Context: TRACE_ID/SPAN_ID;o=OPTIONS
+	cloudTracePattern = regexp.MustCompile(`^([0-9a-fA-F]{32})(?:/([0-9]+))?`)
+	// traceparent: VERSION-TRACE_ID-SPAN_ID-FLAGS
+	traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+)
+
+// RequestContext tags every request with an ID, reusing a well-formed
+// X-Request-ID from the client or generating one, and echoes it in the
+// response. The request context carries a logger with the ID and the
+// request's trace, when it is traced or came with a Cloud Trace header. With
+// project set, traces are logged in the form Cloud Logging links to Cloud
+// Trace.
+func RequestContext(project string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.Request.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Writer.Header().Set("X-Request-ID", requestID)
+
+		logger := logging.FromContext(c.Request.Context()).With("request_id", requestID)
+		if traceID, spanID := parseTrace(c); traceID != "" {
+			logger = logger.With(traceAttrs(project, traceID, spanID)...)
+		}
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+	}
+}
+
+// RequestID returns the ID set by RequestContext, or "" without it
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// ErrorBody is the JSON body of an error response, carrying the request ID
+// so clients can quote it when reporting a problem
+func ErrorBody(c *gin.Context, message string) gin.H {
+	body := gin.H{"error": message}
+	if requestID := RequestID(c); requestID != "" {
+		body["request_id"] = requestID
+	}
+	return body
+}
+
+// validRequestID accepts printable ASCII without spaces so client-supplied
+// IDs stay safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// parseTrace returns the trace and span ID of the request, in hex. The span
+// started by Tracing wins; without one they come from X-Cloud-Trace-Context,
+// whose span ID is decimal, or traceparent.
+func parseTrace(c *gin.Context) (string, string) {
+	if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+		return span.TraceID().String(), span.SpanID().String()
+	}
+	if m := cloudTracePattern.FindStringSubmatch(c.Request.Header.Get("X-Cloud-Trace-Context")); m != nil {
+		spanID := ""
+		if id, err := strconv.ParseUint(m[2], 10, 64); err == nil {
+			spanID = fmt.Sprintf("%016x", id)
+		}
+		return strings.ToLower(m[1]), spanID
+	}
+	if m := traceparentPattern.FindStringSubmatch(c.Request.Header.Get("traceparent")); m != nil {
+		return m[1], m[2]
+	}
+	return "", ""
+}
+
+func traceAttrs(project, traceID, spanID string) []any {
+	if project == "" {
+		attrs := []any{slog.String("trace_id", traceID)}
+		if spanID != "" {
+			attrs = append(attrs, slog.String("span_id", spanID))
+		}
+		return attrs
+	}
+
+	attrs := []any{slog.String("logging.googleapis.com/trace", fmt.Sprintf("projects/%s/traces/%s", project, traceID))}
+	if spanID != "" {
+		attrs = append(attrs, slog.String("logging.googleapis.com/spanId", spanID))
+	}
+	return attrs
+}
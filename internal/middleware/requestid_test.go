@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-backend/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRequestIDRouter logs to the returned buffer and answers every request
+// with an error body
+func setupRequestIDRouter(t *testing.T, project string) (*gin.Engine, *bytes.Buffer) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestContext(project))
+	router.Use(Logger())
+	router.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, ErrorBody(c, "user not found"))
+	})
+	return router, &buf
+}
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestRequestContext_HonoursRequestID(t *testing.T) {
+	router, buf := setupRequestIDRouter(t, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "client-abc-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "client-abc-123", w.Header().Get("X-Request-ID"))
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "user not found", body["error"])
+	assert.Equal(t, "client-abc-123", body["request_id"])
+
+	entry := decodeLogLine(t, buf)
+	assert.Equal(t, "client-abc-123", entry["request_id"])
+	assert.Equal(t, "GET / 404", entry["message"])
+	assert.Equal(t, 404.0, entry["httpRequest"].(map[string]any)["status"])
+}
+
+func TestRequestContext_GeneratesRequestID(t *testing.T) {
+	router, _ := setupRequestIDRouter(t, "")
+
+	for _, incoming := range []string{"", "has spaces", string(make([]byte, 200))} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", incoming)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Len(t, w.Header().Get("X-Request-ID"), 32)
+	}
+}
+
+func TestRequestContext_CloudTrace(t *testing.T) {
+	router, buf := setupRequestIDRouter(t, "my-project")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "105445AA7843BC8BF206B12000100000/123;o=1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := decodeLogLine(t, buf)
+	assert.Equal(t, "projects/my-project/traces/105445aa7843bc8bf206b12000100000", entry["logging.googleapis.com/trace"])
+	assert.Equal(t, "000000000000007b", entry["logging.googleapis.com/spanId"])
+}
+
+func TestRequestContext_Traceparent(t *testing.T) {
+	router, buf := setupRequestIDRouter(t, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := decodeLogLine(t, buf)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", entry["span_id"])
+}
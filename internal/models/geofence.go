@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	GeofenceCircle  = "circle"
+	GeofencePolygon = "polygon"
+)
+
+// Geofence is either a circle (center and radius) or a polygon
+type Geofence struct {
+	ID           int             `json:"id"`
+	OwnerID      int             `json:"owner_id"`
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	Latitude     *float64        `json:"latitude,omitempty"`
+	Longitude    *float64        `json:"longitude,omitempty"`
+	RadiusMeters *float64        `json:"radius_meters,omitempty"`
+	Polygon      *GeoJSONPolygon `json:"polygon,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+type GeofenceRequest struct {
+	Name         string          `json:"name" binding:"required,max=255"`
+	Type         string          `json:"type" binding:"required,oneof=circle polygon"`
+	Latitude     *float64        `json:"latitude" binding:"required_if=Type circle,omitempty,min=-90,max=90"`
+	Longitude    *float64        `json:"longitude" binding:"required_if=Type circle,omitempty,min=-180,max=180"`
+	RadiusMeters *float64        `json:"radius_meters" binding:"required_if=Type circle,omitempty,gt=0,max=100000"`
+	Polygon      *GeoJSONPolygon `json:"polygon" binding:"required_if=Type polygon"`
+}
+
+type GeofenceEvent struct {
+	ID         int64     `json:"id"`
+	GeofenceID int       `json:"geofence_id"`
+	UserID     int       `json:"user_id"`
+	Type       string    `json:"type"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type GeofenceEventsRequest struct {
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// GeoJSON types follow RFC 7946; positions are [longitude, latitude]
+
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *GeoJSONGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONPolygon is a typed Polygon geometry used in request bodies
+type GeoJSONPolygon struct {
+	Type        string        `json:"type" binding:"required,eq=Polygon"`
+	Coordinates [][][]float64 `json:"coordinates" binding:"required"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	VisibilityExact        = "exact"
+	VisibilityFuzzed       = "fuzzed"
+	VisibilityDistanceOnly = "distance_only"
+	VisibilityHidden       = "hidden"
+)
+
+// PrivacySettings controls how a user appears in other users' radar results.
+// FuzzMeters is the grid size positions are snapped to when fuzzed.
+type PrivacySettings struct {
+	Visibility string    `json:"visibility"`
+	FuzzMeters int       `json:"fuzz_meters,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type UpdatePrivacyRequest struct {
+	Visibility string `json:"visibility" binding:"required,oneof=exact fuzzed distance_only hidden"`
+	FuzzMeters int    `json:"fuzz_meters" binding:"omitempty,min=100,max=50000"`
+}
@@ -3,44 +3,241 @@ package models
 import "time"
 
 type User struct {
-	ID        int       `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	Profile      Profile   `json:"profile"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Profile is the part of a user other users may see
+type Profile struct {
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	Bio         string `json:"bio"`
+}
+
+// PublicProfile is returned when looking up another user's profile
+type PublicProfile struct {
+	UserID int `json:"user_id"`
+	Profile
+}
+
+// UpdateProfileRequest replaces the whole profile; empty fields clear it
+type UpdateProfileRequest struct {
+	DisplayName string `json:"display_name" binding:"max=50"`
+	AvatarURL   string `json:"avatar_url" binding:"omitempty,http_url,max=2048"`
+	Bio         string `json:"bio" binding:"max=500"`
 }
 
 type CreateUserRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// UpdateUserRequest is a partial update; nil fields are left unchanged
+type UpdateUserRequest struct {
+	Email *string `json:"email" binding:"omitempty,email"`
+}
+
+type ListUsersRequest struct {
+	Cursor        string     `form:"cursor"`
+	Limit         int        `form:"limit" binding:"omitempty,min=1"`
+	Order         string     `form:"order" binding:"omitempty,oneof=asc desc"`
+	Email         string     `form:"email"`
+	EmailPrefix   string     `form:"email_prefix"`
+	CreatedAfter  *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// FixMetadata is optional detail a device reports along with a position.
+// Accuracy is the horizontal accuracy radius, speed is in meters per second
+// and course is degrees clockwise from true north.
+type FixMetadata struct {
+	AccuracyMeters *float64 `json:"accuracy_meters,omitempty" binding:"omitempty,gte=0"`
+	AltitudeMeters *float64 `json:"altitude_meters,omitempty"`
+	SpeedMps       *float64 `json:"speed_mps,omitempty" binding:"omitempty,gte=0"`
+	CourseDegrees  *float64 `json:"course_degrees,omitempty" binding:"omitempty,gte=0,lt=360"`
 }
 
+// UserRadar is a user's live position. UpdatedAt comes from the server clock
+// for live updates; DeviceTimestamp is when the device says it took the fix.
 type UserRadar struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Latitude  float64   `json:"latitude"`
-	Longitude float64   `json:"longitude"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int     `json:"id"`
+	UserID    int     `json:"user_id"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	IsActive  bool    `json:"is_active"`
+	FixMetadata
+	DeviceTimestamp *time.Time `json:"device_timestamp,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type UpdateLocationRequest struct {
-	UserID    int     `json:"user_id" binding:"required"`
 	Latitude  float64 `json:"latitude" binding:"required,min=-90,max=90"`
 	Longitude float64 `json:"longitude" binding:"required,min=-180,max=180"`
 	IsActive  *bool   `json:"is_active"`
+	FixMetadata
+	DeviceTimestamp *time.Time `json:"device_timestamp"`
 }
 
+// LocationFix is one timestamped position buffered by a device while offline;
+// RecordedAt is the device's timestamp
+type LocationFix struct {
+	Latitude  float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude float64 `json:"longitude" binding:"required,min=-180,max=180"`
+	IsActive  *bool   `json:"is_active"`
+	FixMetadata
+	RecordedAt time.Time `json:"recorded_at" binding:"required"`
+}
+
+// BatchLocationRequest uploads buffered fixes in any order
+type BatchLocationRequest struct {
+	Fixes []LocationFix `json:"fixes" binding:"required,min=1,max=500,dive"`
+}
+
+type LocationPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	IsActive  bool    `json:"is_active"`
+	FixMetadata
+	DeviceTimestamp *time.Time `json:"device_timestamp,omitempty"`
+	RecordedAt      time.Time  `json:"recorded_at"`
+}
+
+type LocationHistoryRequest struct {
+	From   *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit  int        `form:"limit" binding:"omitempty,min=1"`
+	Format string     `form:"format" binding:"omitempty,oneof=json geojson"`
+}
+
+const (
+	NearbyModeRadius  = "radius"
+	NearbyModeNearest = "nearest"
+)
+
+// NearbyUsersRequest searches within Radius km (at most 500), or for the
+// Limit closest users in nearest mode, where Radius is an optional bound
 type NearbyUsersRequest struct {
 	Latitude  float64 `form:"latitude" binding:"required,min=-90,max=90"`
 	Longitude float64 `form:"longitude" binding:"required,min=-180,max=180"`
-	Radius    float64 `form:"radius" binding:"required,min=0"`
+	Radius    float64 `form:"radius" binding:"required_unless=Mode nearest,omitempty,gt=0,max=500"`
+	Mode      string  `form:"mode" binding:"omitempty,oneof=radius nearest"`
+	// MaxAccuracy drops users whose last fix is less accurate than this
+	// many meters, or did not report an accuracy
+	MaxAccuracy float64 `form:"max_accuracy" binding:"omitempty,gt=0"`
+	Limit       int     `form:"limit" binding:"omitempty,min=1"`
+	Offset      int     `form:"offset" binding:"omitempty,min=0,max=1000"`
 }
 
+// BoundingBox is a map viewport given by its south-west and north-east
+// corners; viewports crossing the antimeridian are not supported
+type BoundingBox struct {
+	MinLatitude  *float64 `form:"min_latitude" binding:"required,min=-90,max=90"`
+	MinLongitude *float64 `form:"min_longitude" binding:"required,min=-180,max=180"`
+	MaxLatitude  *float64 `form:"max_latitude" binding:"required,min=-90,max=90"`
+	MaxLongitude *float64 `form:"max_longitude" binding:"required,min=-180,max=180"`
+}
+
+// BoundingBoxRequest lists the users inside a viewport
+type BoundingBoxRequest struct {
+	BoundingBox
+	Limit  int `form:"limit" binding:"omitempty,min=1"`
+	Offset int `form:"offset" binding:"omitempty,min=0,max=1000"`
+}
+
+// ClusterRequest aggregates the users inside a viewport for a web map zoom
+// level
+type ClusterRequest struct {
+	BoundingBox
+	Zoom *int `form:"zoom" binding:"required,min=0,max=22"`
+}
+
+// AreaPageRequest pages the results of a polygon query
+type AreaPageRequest struct {
+	Limit  int `form:"limit" binding:"omitempty,min=1"`
+	Offset int `form:"offset" binding:"omitempty,min=0,max=1000"`
+}
+
+// NearbyStreamRequest is the area watched by the nearby stream
+type NearbyStreamRequest struct {
+	Latitude  float64 `form:"latitude" binding:"required,min=-90,max=90"`
+	Longitude float64 `form:"longitude" binding:"required,min=-180,max=180"`
+	Radius    float64 `form:"radius" binding:"required,gt=0,max=500"`
+}
+
+// NearbyUser is a radar result after the user's privacy settings have been
+// applied: fuzzed positions are snapped to a grid of FuzzMeters and
+// distance-only users carry no coordinates.
 type NearbyUser struct {
 	UserID       int       `json:"user_id"`
-	Email        string    `json:"email"`
-	Latitude     float64   `json:"latitude"`
-	Longitude    float64   `json:"longitude"`
+	Profile      Profile   `json:"profile"`
+	Latitude     *float64  `json:"latitude,omitempty"`
+	Longitude    *float64  `json:"longitude,omitempty"`
 	DistanceKm   float64   `json:"distance_km"`
+	Visibility   string    `json:"visibility"`
+	FuzzMeters   int       `json:"fuzz_meters,omitempty"`
 	LastUpdateAt time.Time `json:"last_update_at"`
 }
+
+// RadarCluster aggregates the users in one grid cell of a cluster query.
+// Latitude and Longitude are the centroid of their positions.
+type RadarCluster struct {
+	Count     int           `json:"count"`
+	Latitude  float64       `json:"latitude"`
+	Longitude float64       `json:"longitude"`
+	Bounds    ClusterBounds `json:"bounds"`
+}
+
+// ClusterBounds is the extent of the positions in a cluster
+type ClusterBounds struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// RadarEvent is streamed to subscribers when a user enters, moves within or
+// leaves their area. Position fields are omitted on leave and otherwise follow
+// the user's privacy settings.
+type RadarEvent struct {
+	Type         string    `json:"type"`
+	UserID       int       `json:"user_id"`
+	Latitude     *float64  `json:"latitude,omitempty"`
+	Longitude    *float64  `json:"longitude,omitempty"`
+	DistanceKm   *float64  `json:"distance_km,omitempty"`
+	Visibility   string    `json:"visibility,omitempty"`
+	FuzzMeters   int       `json:"fuzz_meters,omitempty"`
+	LastUpdateAt time.Time `json:"last_update_at"`
+}
+
+// Actions taken on an implausible location update
+const (
+	FlagActionRejected = "rejected"
+	FlagActionFlagged  = "flagged"
+)
+
+// LocationFlag records a location update whose implied speed was above the
+// limit, for moderation review. SpeedKmh does not count distance within the
+// fixes' accuracy.
+type LocationFlag struct {
+	ID             int64     `json:"id"`
+	UserID         int       `json:"user_id"`
+	FromLatitude   float64   `json:"from_latitude"`
+	FromLongitude  float64   `json:"from_longitude"`
+	ToLatitude     float64   `json:"to_latitude"`
+	ToLongitude    float64   `json:"to_longitude"`
+	DistanceMeters float64   `json:"distance_meters"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	SpeedKmh       float64   `json:"speed_kmh"`
+	Action         string    `json:"action"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// LocationFlagsRequest pages a user's location flags, newest first
+type LocationFlagsRequest struct {
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
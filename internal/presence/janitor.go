@@ -0,0 +1,65 @@
+package presence
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"api-backend/internal/realtime"
+	"api-backend/internal/repository"
+)
+
+// Janitor periodically deactivates radar positions that have not been
+// updated within the presence TTL, so users who lost signal or uninstalled
+// the app drop off the radar. Open streams get a leave event for each.
+type Janitor struct {
+	radar    repository.RadarRepository
+	hub      realtime.Hub
+	interval time.Duration
+}
+
+func NewJanitor(radar repository.RadarRepository, hub realtime.Hub, interval time.Duration) *Janitor {
+	return &Janitor{radar: radar, hub: hub, interval: interval}
+}
+
+// Run sweeps every interval until ctx is cancelled
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.Sweep(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to expire stale radar positions", "error", err)
+			}
+		}
+	}
+}
+
+// Sweep deactivates stale positions once and returns how many were expired
+func (j *Janitor) Sweep(ctx context.Context) (int, error) {
+	expired, err := j.radar.ExpireStale(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Inactive users are outside every area, so privacy settings do not
+	// matter for the resulting leave events
+	for _, radar := range expired {
+		j.hub.Publish(realtime.LocationEvent{
+			UserID:    radar.UserID,
+			Latitude:  radar.Latitude,
+			Longitude: radar.Longitude,
+			IsActive:  false,
+			UpdatedAt: radar.UpdatedAt,
+		})
+	}
+
+	if len(expired) > 0 {
+		slog.InfoContext(ctx, "expired stale radar positions", "count", len(expired))
+	}
+	return len(expired), nil
+}
@@ -0,0 +1,55 @@
+package presence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"api-backend/internal/models"
+	"api-backend/internal/realtime"
+	"api-backend/internal/repository"
+	"api-backend/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJanitor_SweepExpiresStalePositions(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	radar := memory.NewRadarRepository(users, 20*time.Millisecond)
+	hub := realtime.NewMemoryHub(4)
+
+	user, err := users.Create(ctx, "gone@example.com", "")
+	assert.NoError(t, err)
+	_, err = radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1)
+
+	time.Sleep(40 * time.Millisecond)
+
+	nearby, err = radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, nearby, "stale positions are hidden before the janitor runs")
+
+	sub := hub.Subscribe()
+	defer sub.Close()
+
+	expired, err := NewJanitor(radar, hub, time.Minute).Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	event := (<-sub.Events).Location
+	assert.Equal(t, user.ID, event.UserID)
+	assert.False(t, event.IsActive)
+
+	location, err := radar.GetLocation(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, location.IsActive)
+
+	expired, err = NewJanitor(radar, hub, time.Minute).Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, expired)
+}
@@ -0,0 +1,110 @@
+package privacy
+
+import (
+	"math"
+	"sort"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+)
+
+// DefaultFuzzMeters is the grid size used for fuzzed users who did not pick
+// one; distance-only users are measured from a grid cell of this size
+const DefaultFuzzMeters = 1000
+
+// MaxFuzzMeters is the largest grid size users may pick; the validation of
+// models.UpdatePrivacyRequest must match it
+const MaxFuzzMeters = 50000
+
+// DistanceStepKm is the granularity distance-only results are rounded up to.
+// The distance is measured from the user's DefaultFuzzMeters grid cell rather
+// than their exact position, so querying from several points trilaterates at
+// best the cell's center.
+const DistanceStepKm = 0.5
+
+const metersPerDegreeLatitude = 111320.0
+
+// Mask applies a user's privacy settings to a radar result that still holds
+// their exact position, measuring distance from origin. It reports false for
+// hidden users, who must not be shown at all. Radius and area filters must be
+// applied to the masked result, or narrowing them would reveal what masking
+// hides.
+func Mask(origin geo.Point, user models.NearbyUser) (models.NearbyUser, bool) {
+	if user.Latitude == nil || user.Longitude == nil {
+		return models.NearbyUser{}, false
+	}
+	latitude, longitude := *user.Latitude, *user.Longitude
+
+	switch user.Visibility {
+	case models.VisibilityHidden:
+		return models.NearbyUser{}, false
+	case models.VisibilityFuzzed:
+		if user.FuzzMeters <= 0 {
+			user.FuzzMeters = DefaultFuzzMeters
+		}
+		latitude, longitude = Fuzz(latitude, longitude, user.FuzzMeters)
+		user.Latitude = &latitude
+		user.Longitude = &longitude
+		user.DistanceKm = geo.DistanceMeters(origin.Latitude, origin.Longitude, latitude, longitude) / 1000
+	case models.VisibilityDistanceOnly:
+		latitude, longitude = Fuzz(latitude, longitude, DefaultFuzzMeters)
+		distanceKm := geo.DistanceMeters(origin.Latitude, origin.Longitude, latitude, longitude) / 1000
+		user.Latitude = nil
+		user.Longitude = nil
+		user.FuzzMeters = 0
+		user.DistanceKm = math.Max(1, math.Ceil(distanceKm/DistanceStepKm)) * DistanceStepKm
+	default:
+		user.Visibility = models.VisibilityExact
+		user.FuzzMeters = 0
+		user.Latitude = &latitude
+		user.Longitude = &longitude
+	}
+
+	return user, true
+}
+
+// MaskAll masks every result, drops hidden users and re-sorts by the
+// distance that is actually reported
+func MaskAll(origin geo.Point, users []models.NearbyUser) []models.NearbyUser {
+	masked := make([]models.NearbyUser, 0, len(users))
+	for _, user := range users {
+		if user, ok := Mask(origin, user); ok {
+			masked = append(masked, user)
+		}
+	}
+
+	sort.SliceStable(masked, func(i, j int) bool {
+		return masked[i].DistanceKm < masked[j].DistanceKm
+	})
+
+	return masked
+}
+
+// MaxShiftDegrees bounds how far, in degrees of latitude and longitude,
+// masking moves a position whose latitude is within ±maxAbsLatitude. Queries
+// can widen a box on exact positions by it to find every masked one inside.
+func MaxShiftDegrees(maxAbsLatitude float64) (float64, float64) {
+	latStep := MaxFuzzMeters / metersPerDegreeLatitude
+	cos := math.Max(0.01, math.Cos(math.Min(90, maxAbsLatitude+latStep)*math.Pi/180))
+	return latStep, latStep / cos
+}
+
+// Fuzz snaps a position to the center of its cell in a grid of roughly
+// meters by meters. Snapping is deterministic, so averaging repeated queries
+// does not reveal more than a single one.
+func Fuzz(latitude, longitude float64, meters int) (float64, float64) {
+	latStep := float64(meters) / metersPerDegreeLatitude
+	fuzzedLat := (math.Floor(latitude/latStep) + 0.5) * latStep
+	fuzzedLat = math.Max(-90, math.Min(90, fuzzedLat))
+
+	// Longitude cells are widened by latitude so they stay roughly square;
+	// the floor on the cosine keeps cells finite near the poles
+	cos := math.Max(0.01, math.Cos(fuzzedLat*math.Pi/180))
+	lonStep := latStep / cos
+	fuzzedLon := (math.Floor(longitude/lonStep) + 0.5) * lonStep
+	if fuzzedLon > 180 {
+		fuzzedLon -= 360
+	}
+
+	return fuzzedLat, fuzzedLon
+}
@@ -0,0 +1,105 @@
+package privacy
+
+import (
+	"math"
+	"testing"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var berlin = geo.Point{Latitude: 52.5200, Longitude: 13.4050}
+
+func nearbyAt(latitude, longitude float64, visibility string, fuzzMeters int) models.NearbyUser {
+	return models.NearbyUser{
+		UserID:     1,
+		Latitude:   &latitude,
+		Longitude:  &longitude,
+		DistanceKm: geo.DistanceMeters(berlin.Latitude, berlin.Longitude, latitude, longitude) / 1000,
+		Visibility: visibility,
+		FuzzMeters: fuzzMeters,
+	}
+}
+
+func TestFuzz_SnapsToStableGridCell(t *testing.T) {
+	lat1, lon1 := Fuzz(52.52001, 13.40501, 1000)
+	lat2, lon2 := Fuzz(52.52005, 13.40509, 1000)
+	assert.Equal(t, lat1, lat2, "nearby positions in the same cell fuzz identically")
+	assert.Equal(t, lon1, lon2)
+
+	// The snapped point stays within the cell
+	assert.Less(t, geo.DistanceMeters(52.52001, 13.40501, lat1, lon1), 1000.0)
+}
+
+func TestMaxShiftDegrees_BoundsFuzz(t *testing.T) {
+	for _, latitude := range []float64{0, 45.3, 52.52, 70.1, 85.9, -60.7} {
+		dLat, dLon := MaxShiftDegrees(math.Abs(latitude))
+		fuzzedLat, fuzzedLon := Fuzz(latitude, 13.4050, MaxFuzzMeters)
+		assert.LessOrEqual(t, math.Abs(fuzzedLat-latitude), dLat, latitude)
+		assert.LessOrEqual(t, math.Abs(fuzzedLon-13.4050), dLon, latitude)
+	}
+}
+
+func TestMask_Exact(t *testing.T) {
+	user, ok := Mask(berlin, nearbyAt(52.5210, 13.4060, models.VisibilityExact, 0))
+	assert.True(t, ok)
+	assert.InDelta(t, 52.5210, *user.Latitude, 0.000001)
+	assert.InDelta(t, 13.4060, *user.Longitude, 0.000001)
+}
+
+func TestMask_Fuzzed(t *testing.T) {
+	user, ok := Mask(berlin, nearbyAt(52.5210, 13.4060, models.VisibilityFuzzed, 0))
+	assert.True(t, ok)
+	assert.Equal(t, DefaultFuzzMeters, user.FuzzMeters)
+	assert.NotEqual(t, 52.5210, *user.Latitude)
+
+	// Distance is measured to the fuzzed point, not the exact one
+	expected := geo.DistanceMeters(berlin.Latitude, berlin.Longitude, *user.Latitude, *user.Longitude) / 1000
+	assert.InDelta(t, expected, user.DistanceKm, 0.000001)
+}
+
+func TestMask_DistanceOnly(t *testing.T) {
+	user, ok := Mask(berlin, nearbyAt(52.5300, 13.4150, models.VisibilityDistanceOnly, 0))
+	assert.True(t, ok)
+	assert.Nil(t, user.Latitude)
+	assert.Nil(t, user.Longitude)
+
+	// The distance is measured from the default grid cell and rounded up
+	cellLat, cellLon := Fuzz(52.5300, 13.4150, DefaultFuzzMeters)
+	cellKm := geo.DistanceMeters(berlin.Latitude, berlin.Longitude, cellLat, cellLon) / 1000
+	assert.Equal(t, math.Ceil(cellKm/DistanceStepKm)*DistanceStepKm, user.DistanceKm)
+
+	user, _ = Mask(berlin, nearbyAt(52.5200, 13.4050, models.VisibilityDistanceOnly, 0))
+	assert.GreaterOrEqual(t, user.DistanceKm, DistanceStepKm, "zero distance is not revealed")
+}
+
+func TestMask_DistanceOnlyHidesMovesWithinCell(t *testing.T) {
+	// Two positions in the same cell report the same distance from any
+	// origin, so moving the origin around cannot tell them apart
+	lat1, lon1 := 52.52001, 13.40501
+	lat2, lon2 := 52.52005, 13.40509
+	for _, origin := range []geo.Point{berlin, {Latitude: 52.53, Longitude: 13.39}, {Latitude: 52.51, Longitude: 13.42}} {
+		first, _ := Mask(origin, nearbyAt(lat1, lon1, models.VisibilityDistanceOnly, 0))
+		second, _ := Mask(origin, nearbyAt(lat2, lon2, models.VisibilityDistanceOnly, 0))
+		assert.Equal(t, first.DistanceKm, second.DistanceKm)
+	}
+}
+
+func TestMask_Hidden(t *testing.T) {
+	_, ok := Mask(berlin, nearbyAt(52.5210, 13.4060, models.VisibilityHidden, 0))
+	assert.False(t, ok)
+}
+
+func TestMaskAll_DropsHiddenAndSortsByReportedDistance(t *testing.T) {
+	users := MaskAll(berlin, []models.NearbyUser{
+		nearbyAt(52.5201, 13.4051, models.VisibilityDistanceOnly, 0),
+		nearbyAt(52.5210, 13.4060, models.VisibilityHidden, 0),
+		nearbyAt(52.5230, 13.4080, models.VisibilityExact, 0),
+	})
+
+	assert.Len(t, users, 2)
+	assert.NotNil(t, users[0].Latitude, "the exact user at ~0.4 km sorts before the rounded 0.5 km")
+	assert.Nil(t, users[1].Latitude)
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often stores drop buckets that are full again
+const pruneInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory, so each instance of the API
+// limits on its own
+type MemoryStore struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[string]time.Time
+	lastPrune time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		buckets: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= pruneInterval {
+		for bucketKey, tat := range s.buckets {
+			if tat.Before(now) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	tat, result := take(s.buckets[key], now, policy)
+	s.buckets[key] = tat
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	"api-backend/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limits table so every instance of
+// the API shares them. All times come from the database clock.
+type PostgresStore struct {
+	db *database.Database
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewPostgresStore(db *database.Database) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	ctx, cancel := s.db.WithTimeout(ctx)
+	defer cancel()
+
+	s.prune(ctx)
+
+	// The update only happens when the request fits, so a returned row means
+	// the request was allowed
+	query := `
+		INSERT INTO rate_limits (key, tat)
+		VALUES ($1, now() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE
+		SET tat = GREATEST(rate_limits.tat, now()) + make_interval(secs => $2)
+		WHERE GREATEST(rate_limits.tat, now()) + make_interval(secs => $2) <= now() + make_interval(secs => $3)
+		RETURNING EXTRACT(EPOCH FROM tat - now())
+	`
+
+	var untilFull float64
+	err := s.db.DB.QueryRowContext(ctx, query, key, policy.interval().Seconds(), policy.Period.Seconds()).Scan(&untilFull)
+	if err == nil {
+		return allowed(secondsDuration(untilFull), policy), nil
+	}
+	if err != sql.ErrNoRows {
+		return Result{}, err
+	}
+
+	err = s.db.DB.QueryRowContext(ctx,
+		"SELECT GREATEST(EXTRACT(EPOCH FROM tat - now()), 0) FROM rate_limits WHERE key = $1",
+		key,
+	).Scan(&untilFull)
+	if err != nil {
+		return Result{}, err
+	}
+	return denied(secondsDuration(untilFull), policy), nil
+}
+
+// prune deletes buckets that are full again at most once per pruneInterval,
+// so the table only holds recently limited clients
+func (s *PostgresStore) prune(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastPrune) < pruneInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = time.Now()
+	s.mu.Unlock()
+
+	if _, err := s.db.DB.ExecContext(ctx, "DELETE FROM rate_limits WHERE tat < now()"); err != nil {
+		slog.ErrorContext(ctx, "failed to prune rate limits", "error", err)
+	}
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Policy allows Limit requests per Period. Requests refill continuously, so a
+// client can burst up to Limit at once and then sustain Limit/Period.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// interval is how long one request takes to refill
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// Result is the outcome of taking one request from a bucket
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+	// RetryAfter is how long until a request is allowed again; zero when
+	// this one was
+	RetryAfter time.Duration
+}
+
+// Store keeps one token bucket per key. Buckets are stored as their
+// theoretical arrival time (GCRA), the moment they would be full again, so a
+// bucket is a single timestamp that can be updated atomically.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// take applies one request at now to a bucket that is full again at tat and
+// returns the bucket's new tat
+func take(tat, now time.Time, policy Policy) (time.Time, Result) {
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(policy.interval())
+	if next.Sub(now) > policy.Period {
+		return tat, denied(tat.Sub(now), policy)
+	}
+	return next, allowed(next.Sub(now), policy)
+}
+
+// allowed describes an accepted request given how long its bucket now needs
+// to refill
+func allowed(untilFull time.Duration, policy Policy) Result {
+	return Result{
+		Allowed:    true,
+		Remaining:  int((policy.Period - untilFull) / policy.interval()),
+		ResetAfter: untilFull,
+	}
+}
+
+// denied describes a refused request given how long its bucket needs to
+// refill
+func denied(untilFull time.Duration, policy Policy) Result {
+	return Result{
+		ResetAfter: untilFull,
+		RetryAfter: untilFull + policy.interval() - policy.Period,
+	}
+}
+
+// Seconds rounds a duration up to whole seconds for HTTP headers
+func Seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"api-backend/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var perMinute = Policy{Name: "test", Limit: 3, Period: time.Minute}
+
+// fakeClock lets tests move a MemoryStore through time
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestMemoryStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestMemoryStore_BurstThenLimit(t *testing.T) {
+	store, _ := newTestMemoryStore()
+	ctx := context.Background()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := store.Take(ctx, "alice", perMinute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "alice", perMinute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 20*time.Second, result.RetryAfter)
+	assert.Equal(t, time.Minute, result.ResetAfter)
+
+	// Other keys have their own bucket
+	result, err = store.Take(ctx, "bob", perMinute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryStore_Refill(t *testing.T) {
+	store, clock := newTestMemoryStore()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		store.Take(ctx, "alice", perMinute)
+	}
+
+	// One request refills every 20 seconds
+	clock.now = clock.now.Add(20 * time.Second)
+	result, _ := store.Take(ctx, "alice", perMinute)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, _ = store.Take(ctx, "alice", perMinute)
+	assert.False(t, result.Allowed)
+
+	// An idle bucket fills up to the limit but not beyond it
+	clock.now = clock.now.Add(time.Hour)
+	result, _ = store.Take(ctx, "alice", perMinute)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+	assert.Equal(t, 20*time.Second, result.ResetAfter)
+}
+
+func TestMemoryStore_PrunesFullBuckets(t *testing.T) {
+	store, clock := newTestMemoryStore()
+	ctx := context.Background()
+
+	store.Take(ctx, "alice", perMinute)
+	clock.now = clock.now.Add(2 * pruneInterval)
+	store.Take(ctx, "bob", perMinute)
+
+	assert.NotContains(t, store.buckets, "alice")
+	assert.Contains(t, store.buckets, "bob")
+}
+
+func TestPostgresStore_Take(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("DATABASE_URL not set, skipping Postgres integration test")
+	}
+
+	db, err := database.New(databaseURL, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.RunMigrations())
+	db.DB.Exec("DELETE FROM rate_limits")
+
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := store.Take(ctx, "alice", perMinute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "alice", perMinute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, 20*time.Second, result.RetryAfter, float64(time.Second))
+
+	result, err = store.Take(ctx, "bob", perMinute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
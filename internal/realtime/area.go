@@ -0,0 +1,123 @@
+package realtime
+
+import (
+	"time"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+	"api-backend/internal/privacy"
+)
+
+const (
+	EventEnter = "enter"
+	EventMove  = "move"
+	EventLeave = "leave"
+)
+
+// Area is the circle a stream subscriber is watching
+type Area struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+// AreaTracker turns raw location events into enter/move/leave events for a
+// single subscriber by remembering which users are currently inside its area.
+// Excluded users, such as the subscriber and users they blocked, are never
+// reported as inside.
+type AreaTracker struct {
+	area     Area
+	inside   map[int]bool
+	excluded map[int]bool
+}
+
+func NewAreaTracker(area Area) *AreaTracker {
+	return &AreaTracker{area: area, inside: make(map[int]bool), excluded: make(map[int]bool)}
+}
+
+// Exclude hides userID from this tracker and returns a leave event if they
+// were inside
+func (t *AreaTracker) Exclude(userID int) (models.RadarEvent, bool) {
+	t.excluded[userID] = true
+	if !t.inside[userID] {
+		return models.RadarEvent{}, false
+	}
+
+	delete(t.inside, userID)
+	return models.RadarEvent{Type: EventLeave, UserID: userID, LastUpdateAt: time.Now().UTC()}, true
+}
+
+// ApplyBlock excludes or includes the other party when viewerID is one side
+// of a block change, returning a leave event if a newly blocked user was
+// inside
+func (t *AreaTracker) ApplyBlock(viewerID int, event BlockEvent) (models.RadarEvent, bool) {
+	var other int
+	switch viewerID {
+	case event.BlockerID:
+		other = event.BlockedID
+	case event.BlockedID:
+		other = event.BlockerID
+	default:
+		return models.RadarEvent{}, false
+	}
+
+	if event.Blocked {
+		return t.Exclude(other)
+	}
+	t.Include(other)
+	return models.RadarEvent{}, false
+}
+
+// Include reverses Exclude; the user shows up again with their next location
+// event
+func (t *AreaTracker) Include(userID int) {
+	delete(t.excluded, userID)
+}
+
+// Apply returns the radar event a location change produces for this area, if
+// any. Inactive and hidden users are treated as outside; positions of users
+// inside are masked according to their privacy settings, and the masked
+// distance decides whether they are inside, like in /nearby.
+func (t *AreaTracker) Apply(event LocationEvent) (models.RadarEvent, bool) {
+	center := geo.Point{Latitude: t.area.Latitude, Longitude: t.area.Longitude}
+	distanceKm := geo.DistanceMeters(center.Latitude, center.Longitude, event.Latitude, event.Longitude) / 1000
+	masked, visible := privacy.Mask(center, models.NearbyUser{
+		UserID:     event.UserID,
+		Latitude:   &event.Latitude,
+		Longitude:  &event.Longitude,
+		DistanceKm: distanceKm,
+		Visibility: event.Visibility,
+		FuzzMeters: event.FuzzMeters,
+	})
+	isInside := event.IsActive && visible && !t.excluded[event.UserID] && masked.DistanceKm <= t.area.RadiusKm
+	wasInside := t.inside[event.UserID]
+
+	var eventType string
+	switch {
+	case isInside && !wasInside:
+		eventType = EventEnter
+		t.inside[event.UserID] = true
+	case isInside && wasInside:
+		eventType = EventMove
+	case !isInside && wasInside:
+		eventType = EventLeave
+		delete(t.inside, event.UserID)
+	default:
+		return models.RadarEvent{}, false
+	}
+
+	radarEvent := models.RadarEvent{
+		Type:         eventType,
+		UserID:       event.UserID,
+		LastUpdateAt: event.UpdatedAt,
+	}
+	if isInside {
+		radarEvent.Latitude = masked.Latitude
+		radarEvent.Longitude = masked.Longitude
+		radarEvent.DistanceKm = &masked.DistanceKm
+		radarEvent.Visibility = masked.Visibility
+		radarEvent.FuzzMeters = masked.FuzzMeters
+	}
+
+	return radarEvent, true
+}
@@ -0,0 +1,143 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// LocationEvent is published whenever a user's radar position or privacy
+// settings change. The position is exact; subscribers mask it.
+type LocationEvent struct {
+	UserID     int
+	Latitude   float64
+	Longitude  float64
+	IsActive   bool
+	Visibility string
+	FuzzMeters int
+	UpdatedAt  time.Time
+}
+
+// BlockEvent is published when a user blocks or unblocks another user, so
+// open streams of either user can hide or show the other
+type BlockEvent struct {
+	BlockerID int
+	BlockedID int
+	Blocked   bool
+}
+
+// Event is what subscribers receive; exactly one field is set
+type Event struct {
+	Location *LocationEvent
+	Block    *BlockEvent
+}
+
+// Hub fans location and block events out to subscribers. MemoryHub only reaches
+// subscribers in the same process; a Postgres LISTEN/NOTIFY implementation
+// can satisfy the same interface to span instances.
+type Hub interface {
+	Publish(event LocationEvent)
+	PublishBlock(event BlockEvent)
+	Subscribe() *Subscription
+	// Close ends every subscription, and any made afterwards right away, so
+	// open streams finish when the server shuts down
+	Close()
+}
+
+// Subscription receives published events until Close is called. Events is
+// closed when the subscription ends, including when the hub drops a
+// subscriber that stopped keeping up.
+type Subscription struct {
+	Events <-chan Event
+	close  func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+type subscriber struct {
+	events chan Event
+}
+
+// MemoryHub is an in-process Hub
+type MemoryHub struct {
+	mu          sync.RWMutex
+	bufferSize  int
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+func NewMemoryHub(bufferSize int) *MemoryHub {
+	return &MemoryHub{
+		bufferSize:  bufferSize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish delivers event to every subscriber without blocking. A subscriber
+// whose buffer is full is disconnected rather than silently missing events,
+// since a gap would leave its enter/leave state wrong; clients reconnect and
+// receive a fresh snapshot.
+func (h *MemoryHub) Publish(event LocationEvent) {
+	h.publish(Event{Location: &event})
+}
+
+func (h *MemoryHub) PublishBlock(event BlockEvent) {
+	h.publish(Event{Block: &event})
+}
+
+func (h *MemoryHub) publish(event Event) {
+	var slow []*subscriber
+
+	h.mu.RLock()
+	for s := range h.subscribers {
+		select {
+		case s.events <- event:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		h.remove(s)
+	}
+}
+
+func (h *MemoryHub) Subscribe() *Subscription {
+	s := &subscriber{events: make(chan Event, h.bufferSize)}
+
+	h.mu.Lock()
+	if h.closed {
+		close(s.events)
+	} else {
+		h.subscribers[s] = struct{}{}
+	}
+	h.mu.Unlock()
+
+	return &Subscription{
+		Events: s.events,
+		close:  func() { h.remove(s) },
+	}
+}
+
+func (h *MemoryHub) remove(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
+
+func (h *MemoryHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subscribers {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
@@ -0,0 +1,149 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"api-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAreaTracker_Transitions(t *testing.T) {
+	// 10 km around central Berlin
+	tracker := NewAreaTracker(Area{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10})
+
+	inside := LocationEvent{UserID: 1, Latitude: 52.5210, Longitude: 13.4060, IsActive: true, UpdatedAt: time.Now()}
+	nearby := LocationEvent{UserID: 1, Latitude: 52.5300, Longitude: 13.4150, IsActive: true, UpdatedAt: time.Now()}
+	outside := LocationEvent{UserID: 1, Latitude: 48.1351, Longitude: 11.5820, IsActive: true, UpdatedAt: time.Now()}
+
+	_, ok := tracker.Apply(outside)
+	assert.False(t, ok, "moving outside the area is not interesting")
+
+	event, ok := tracker.Apply(inside)
+	assert.True(t, ok)
+	assert.Equal(t, EventEnter, event.Type)
+	assert.InDelta(t, 52.5210, *event.Latitude, 0.0001)
+	assert.Less(t, *event.DistanceKm, 1.0)
+
+	event, ok = tracker.Apply(nearby)
+	assert.True(t, ok)
+	assert.Equal(t, EventMove, event.Type)
+
+	event, ok = tracker.Apply(outside)
+	assert.True(t, ok)
+	assert.Equal(t, EventLeave, event.Type)
+	assert.Nil(t, event.Latitude, "leave events do not reveal the new position")
+
+	_, ok = tracker.Apply(outside)
+	assert.False(t, ok)
+}
+
+func TestAreaTracker_InactiveLeaves(t *testing.T) {
+	tracker := NewAreaTracker(Area{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10})
+
+	event, _ := tracker.Apply(LocationEvent{UserID: 7, Latitude: 52.52, Longitude: 13.405, IsActive: true})
+	assert.Equal(t, EventEnter, event.Type)
+
+	event, ok := tracker.Apply(LocationEvent{UserID: 7, Latitude: 52.52, Longitude: 13.405, IsActive: false})
+	assert.True(t, ok)
+	assert.Equal(t, EventLeave, event.Type)
+}
+
+func TestAreaTracker_UsesMaskedDistance(t *testing.T) {
+	// The exact position is 2.4 km from the center, but the fuzzed one and
+	// the rounded distance are outside the 2.5 km radius
+	tracker := NewAreaTracker(Area{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 2.5})
+
+	for userID, visibility := range map[int]string{1: models.VisibilityFuzzed, 2: models.VisibilityDistanceOnly} {
+		_, ok := tracker.Apply(LocationEvent{UserID: userID, Latitude: 52.5350, Longitude: 13.4300, IsActive: true, Visibility: visibility})
+		assert.False(t, ok, visibility)
+	}
+
+	event, ok := tracker.Apply(LocationEvent{UserID: 3, Latitude: 52.5350, Longitude: 13.4300, IsActive: true, Visibility: models.VisibilityExact})
+	assert.True(t, ok)
+	assert.Equal(t, EventEnter, event.Type)
+}
+
+func TestAreaTracker_Blocks(t *testing.T) {
+	tracker := NewAreaTracker(Area{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10})
+	inside := LocationEvent{UserID: 2, Latitude: 52.5210, Longitude: 13.4060, IsActive: true}
+
+	_, ok := tracker.Apply(inside)
+	assert.True(t, ok)
+
+	// Blocks between other users do not affect this viewer
+	_, ok = tracker.ApplyBlock(1, BlockEvent{BlockerID: 3, BlockedID: 2, Blocked: true})
+	assert.False(t, ok)
+
+	// Being blocked hides the blocker just like blocking them
+	event, ok := tracker.ApplyBlock(1, BlockEvent{BlockerID: 2, BlockedID: 1, Blocked: true})
+	assert.True(t, ok)
+	assert.Equal(t, EventLeave, event.Type)
+	assert.Equal(t, 2, event.UserID)
+
+	_, ok = tracker.Apply(inside)
+	assert.False(t, ok, "blocked users produce no events")
+
+	_, ok = tracker.ApplyBlock(1, BlockEvent{BlockerID: 2, BlockedID: 1, Blocked: false})
+	assert.False(t, ok)
+	event, ok = tracker.Apply(inside)
+	assert.True(t, ok)
+	assert.Equal(t, EventEnter, event.Type)
+}
+
+func TestMemoryHub_PublishSubscribe(t *testing.T) {
+	hub := NewMemoryHub(4)
+
+	first := hub.Subscribe()
+	second := hub.Subscribe()
+
+	hub.Publish(LocationEvent{UserID: 1})
+
+	assert.Equal(t, 1, (<-first.Events).Location.UserID)
+	assert.Equal(t, 1, (<-second.Events).Location.UserID)
+
+	first.Close()
+	_, open := <-first.Events
+	assert.False(t, open)
+
+	// Closing twice is harmless and other subscribers keep receiving
+	first.Close()
+	hub.Publish(LocationEvent{UserID: 2})
+	assert.Equal(t, 2, (<-second.Events).Location.UserID)
+
+	hub.PublishBlock(BlockEvent{BlockerID: 1, BlockedID: 2, Blocked: true})
+	event := <-second.Events
+	assert.Nil(t, event.Location)
+	assert.Equal(t, BlockEvent{BlockerID: 1, BlockedID: 2, Blocked: true}, *event.Block)
+}
+
+func TestMemoryHub_DropsSlowSubscriber(t *testing.T) {
+	hub := NewMemoryHub(1)
+	sub := hub.Subscribe()
+
+	hub.Publish(LocationEvent{UserID: 1})
+	hub.Publish(LocationEvent{UserID: 2})
+
+	assert.Equal(t, 1, (<-sub.Events).Location.UserID)
+	_, open := <-sub.Events
+	assert.False(t, open, "a subscriber that falls behind is disconnected")
+}
+
+func TestMemoryHub_Close(t *testing.T) {
+	hub := NewMemoryHub(4)
+	before := hub.Subscribe()
+
+	hub.Close()
+	_, open := <-before.Events
+	assert.False(t, open)
+
+	after := hub.Subscribe()
+	_, open = <-after.Events
+	assert.False(t, open, "subscriptions after Close end immediately")
+
+	// Publishing and closing subscriptions after Close are harmless
+	hub.Publish(LocationEvent{UserID: 1})
+	before.Close()
+	after.Close()
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+	"api-backend/internal/repository"
+)
+
+// GeofenceRepository is an in-memory repository.GeofenceRepository
+type GeofenceRepository struct {
+	mu          sync.RWMutex
+	nextID      int
+	nextEventID int64
+	fences      map[int]models.Geofence
+	events      []models.GeofenceEvent
+}
+
+func NewGeofenceRepository() *GeofenceRepository {
+	return &GeofenceRepository{
+		nextID:      1,
+		nextEventID: 1,
+		fences:      make(map[int]models.Geofence),
+	}
+}
+
+func (r *GeofenceRepository) Create(ctx context.Context, fence models.Geofence) (*models.Geofence, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := now()
+	fence.ID = r.nextID
+	fence.CreatedAt = now
+	fence.UpdatedAt = now
+	r.fences[fence.ID] = fence
+	r.nextID++
+
+	return &fence, nil
+}
+
+func (r *GeofenceRepository) GetByID(ctx context.Context, id int) (*models.Geofence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fence, ok := r.fences[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &fence, nil
+}
+
+func (r *GeofenceRepository) ListByOwner(ctx context.Context, ownerID int) ([]models.Geofence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fences := []models.Geofence{}
+	for _, fence := range r.fences {
+		if fence.OwnerID == ownerID {
+			fences = append(fences, fence)
+		}
+	}
+
+	sort.Slice(fences, func(i, j int) bool {
+		return fences[i].ID < fences[j].ID
+	})
+
+	return fences, nil
+}
+
+func (r *GeofenceRepository) Update(ctx context.Context, fence models.Geofence) (*models.Geofence, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.fences[fence.ID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	fence.CreatedAt = existing.CreatedAt
+	fence.UpdatedAt = now()
+	r.fences[fence.ID] = fence
+
+	return &fence, nil
+}
+
+func (r *GeofenceRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.fences[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.fences, id)
+
+	// Mirror ON DELETE CASCADE
+	events := r.events[:0]
+	for _, event := range r.events {
+		if event.GeofenceID != id {
+			events = append(events, event)
+		}
+	}
+	r.events = events
+
+	return nil
+}
+
+func (r *GeofenceRepository) RecordTransitions(ctx context.Context, userID int, previous *geo.Point, current geo.Point) ([]models.GeofenceEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]int, 0, len(r.fences))
+	for id, fence := range r.fences {
+		if fence.OwnerID == userID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	recorded := []models.GeofenceEvent{}
+	for _, id := range ids {
+		fence := r.fences[id]
+		insideNow := fenceContains(fence, current)
+		insideBefore := previous != nil && fenceContains(fence, *previous)
+		if insideNow == insideBefore {
+			continue
+		}
+
+		eventType := "exit"
+		if insideNow {
+			eventType = "enter"
+		}
+
+		event := models.GeofenceEvent{
+			ID:         r.nextEventID,
+			GeofenceID: id,
+			UserID:     userID,
+			Type:       eventType,
+			Latitude:   current.Latitude,
+			Longitude:  current.Longitude,
+			OccurredAt: now(),
+		}
+		r.nextEventID++
+		r.events = append(r.events, event)
+		recorded = append(recorded, event)
+	}
+
+	return recorded, nil
+}
+
+func (r *GeofenceRepository) ListEvents(ctx context.Context, filter repository.GeofenceEventFilter) ([]models.GeofenceEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Events are appended in order, so walk backwards for newest first
+	events := []models.GeofenceEvent{}
+	for i := len(r.events) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		event := r.events[i]
+		if filter.UserID != 0 && event.UserID != filter.UserID {
+			continue
+		}
+		if filter.GeofenceID != 0 && event.GeofenceID != filter.GeofenceID {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func fenceContains(fence models.Geofence, point geo.Point) bool {
+	switch fence.Type {
+	case models.GeofenceCircle:
+		if fence.Latitude == nil || fence.Longitude == nil || fence.RadiusMeters == nil {
+			return false
+		}
+		return geo.DistanceMeters(*fence.Latitude, *fence.Longitude, point.Latitude, point.Longitude) <= *fence.RadiusMeters
+	case models.GeofencePolygon:
+		if fence.Polygon == nil {
+			return false
+		}
+		polygon, err := geo.PolygonFromGeoJSON(fence.Polygon.Coordinates)
+		return err == nil && polygon.Contains(point)
+	}
+	return false
+}
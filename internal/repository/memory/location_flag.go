@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"api-backend/internal/models"
+)
+
+// LocationFlagRepository is an in-memory repository.LocationFlagRepository
+type LocationFlagRepository struct {
+	mu     sync.RWMutex
+	nextID int64
+	flags  []models.LocationFlag
+}
+
+func NewLocationFlagRepository() *LocationFlagRepository {
+	return &LocationFlagRepository{nextID: 1}
+}
+
+func (r *LocationFlagRepository) Create(ctx context.Context, flag models.LocationFlag) (*models.LocationFlag, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	flag.ID = r.nextID
+	flag.CreatedAt = now()
+	r.nextID++
+	r.flags = append(r.flags, flag)
+
+	return &flag, nil
+}
+
+func (r *LocationFlagRepository) ListByUser(ctx context.Context, userID int, limit int) ([]models.LocationFlag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Flags are appended in creation order, so walk backwards for newest first
+	flags := []models.LocationFlag{}
+	for i := len(r.flags) - 1; i >= 0 && len(flags) < limit; i-- {
+		if r.flags[i].UserID == userID {
+			flags = append(flags, r.flags[i])
+		}
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,384 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+	"api-backend/internal/privacy"
+	"api-backend/internal/repository"
+)
+
+// RadarRepository is an in-memory repository.RadarRepository. Distances are
+// computed with the haversine formula rather than PostGIS, so results can
+// differ from Postgres by a fraction of a percent.
+type RadarRepository struct {
+	mu          sync.RWMutex
+	users       *UserRepository
+	presenceTTL time.Duration
+	nextID      int
+	radar       map[int]models.UserRadar
+	history     map[int][]models.LocationPoint
+	privacy     map[int]models.PrivacySettings
+}
+
+func NewRadarRepository(users *UserRepository, presenceTTL time.Duration) *RadarRepository {
+	return &RadarRepository{
+		users:       users,
+		presenceTTL: presenceTTL,
+		nextID:      1,
+		radar:       make(map[int]models.UserRadar),
+		history:     make(map[int][]models.LocationPoint),
+		privacy:     make(map[int]models.PrivacySettings),
+	}
+}
+
+func (r *RadarRepository) UpsertLocation(ctx context.Context, userID int, fix models.LocationPoint, check repository.LocationCheck) (*models.UserRadar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.radar[userID]
+	if check != nil {
+		if err := check(current(entry, ok)); err != nil {
+			return nil, err
+		}
+	}
+
+	now := now()
+	if !ok {
+		entry = models.UserRadar{ID: r.nextID, UserID: userID, CreatedAt: now}
+		r.nextID++
+	}
+
+	entry.Latitude = fix.Latitude
+	entry.Longitude = fix.Longitude
+	entry.IsActive = fix.IsActive
+	entry.FixMetadata = fix.FixMetadata
+	entry.DeviceTimestamp = fix.DeviceTimestamp
+	entry.UpdatedAt = now
+	r.radar[userID] = entry
+
+	fix.RecordedAt = now
+	r.history[userID] = append(r.history[userID], fix)
+
+	return &entry, nil
+}
+
+func (r *RadarRepository) AppendLocations(ctx context.Context, userID int, fixes []models.LocationPoint, filter repository.LocationFilter) (*models.UserRadar, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.radar[userID]
+	if filter != nil {
+		fixes = filter(current(entry, ok), fixes)
+	}
+	accepted := make([]models.LocationPoint, 0, len(fixes))
+	for _, fix := range fixes {
+		if ok && !fix.RecordedAt.After(entry.UpdatedAt) {
+			continue
+		}
+		accepted = append(accepted, fix)
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].RecordedAt.Before(accepted[j].RecordedAt)
+	})
+
+	if len(accepted) == 0 {
+		if !ok {
+			return nil, 0, repository.ErrNotFound
+		}
+		return &entry, 0, nil
+	}
+
+	if !ok {
+		entry = models.UserRadar{ID: r.nextID, UserID: userID, CreatedAt: now()}
+		r.nextID++
+	}
+
+	newest := accepted[len(accepted)-1]
+	entry.Latitude = newest.Latitude
+	entry.Longitude = newest.Longitude
+	entry.IsActive = newest.IsActive
+	entry.FixMetadata = newest.FixMetadata
+	entry.DeviceTimestamp = newest.DeviceTimestamp
+	entry.UpdatedAt = newest.RecordedAt
+	r.radar[userID] = entry
+	r.history[userID] = append(r.history[userID], accepted...)
+
+	return &entry, len(accepted), nil
+}
+
+// current returns a copy of a radar entry for checks, or nil if there was none
+func current(entry models.UserRadar, ok bool) *models.UserRadar {
+	if !ok {
+		return nil
+	}
+	return &entry
+}
+
+func (r *RadarRepository) GetLocation(ctx context.Context, userID int) (*models.UserRadar, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.radar[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (r *RadarRepository) FindNearby(ctx context.Context, filter repository.NearbyFilter) ([]models.NearbyUser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	origin := geo.Point{Latitude: filter.Latitude, Longitude: filter.Longitude}
+	staleBefore := now().Add(-r.presenceTTL)
+	type match struct {
+		user       models.NearbyUser
+		reportedKm float64
+	}
+	matches := []match{}
+	for _, entry := range r.radar {
+		user, settings, ok := r.visible(entry, filter.ViewerID, staleBefore)
+		if !ok {
+			continue
+		}
+
+		if filter.MaxAccuracyMeters > 0 && (entry.AccuracyMeters == nil || *entry.AccuracyMeters > filter.MaxAccuracyMeters) {
+			continue
+		}
+
+		entryLat, entryLon := entry.Latitude, entry.Longitude
+		nearby := models.NearbyUser{
+			UserID:       entry.UserID,
+			Profile:      user.Profile,
+			Latitude:     &entryLat,
+			Longitude:    &entryLon,
+			DistanceKm:   geo.DistanceMeters(filter.Latitude, filter.Longitude, entry.Latitude, entry.Longitude) / 1000,
+			Visibility:   settings.Visibility,
+			FuzzMeters:   settings.FuzzMeters,
+			LastUpdateAt: entry.UpdatedAt,
+		}
+
+		// Filter and order on what the viewer will be shown, so narrowing
+		// the radius or area reveals no more than the masked result does
+		masked, _ := privacy.Mask(origin, nearby)
+		if filter.RadiusKm > 0 && masked.DistanceKm > filter.RadiusKm {
+			continue
+		}
+		if filter.Area != nil && (masked.Latitude == nil || !filter.Area.Contains(geo.Point{Latitude: *masked.Latitude, Longitude: *masked.Longitude})) {
+			continue
+		}
+
+		matches = append(matches, match{user: nearby, reportedKm: masked.DistanceKm})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].reportedKm != matches[j].reportedKm {
+			return matches[i].reportedKm < matches[j].reportedKm
+		}
+		return matches[i].user.UserID < matches[j].user.UserID
+	})
+
+	if filter.Offset >= len(matches) {
+		return []models.NearbyUser{}, nil
+	}
+	matches = matches[filter.Offset:]
+	if filter.Limit > 0 && len(matches) > filter.Limit {
+		matches = matches[:filter.Limit]
+	}
+
+	nearbyUsers := make([]models.NearbyUser, 0, len(matches))
+	for _, m := range matches {
+		nearbyUsers = append(nearbyUsers, m.user)
+	}
+
+	return nearbyUsers, nil
+}
+
+func (r *RadarRepository) Clusters(ctx context.Context, filter repository.ClusterFilter) ([]models.RadarCluster, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inBox := func(latitude, longitude float64) bool {
+		return latitude >= filter.Min.Latitude && latitude <= filter.Max.Latitude &&
+			longitude >= filter.Min.Longitude && longitude <= filter.Max.Longitude
+	}
+
+	type cell struct{ x, y float64 }
+	type bucket struct {
+		cluster   models.RadarCluster
+		latitude  float64
+		longitude float64
+	}
+
+	staleBefore := now().Add(-r.presenceTTL)
+	buckets := make(map[cell]*bucket)
+	for _, entry := range r.radar {
+		_, settings, ok := r.visible(entry, filter.ViewerID, staleBefore)
+		if !ok {
+			continue
+		}
+
+		latitude, longitude := entry.Latitude, entry.Longitude
+		switch settings.Visibility {
+		case models.VisibilityDistanceOnly:
+			continue
+		case models.VisibilityFuzzed:
+			meters := settings.FuzzMeters
+			if meters <= 0 {
+				meters = privacy.DefaultFuzzMeters
+			}
+			latitude, longitude = privacy.Fuzz(latitude, longitude, meters)
+		}
+		if !inBox(latitude, longitude) {
+			continue
+		}
+
+		key := cell{math.Floor(longitude / filter.CellDegrees), math.Floor(latitude / filter.CellDegrees)}
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{cluster: models.RadarCluster{Bounds: models.ClusterBounds{
+				MinLatitude: latitude, MinLongitude: longitude, MaxLatitude: latitude, MaxLongitude: longitude,
+			}}}
+			buckets[key] = b
+		}
+		b.cluster.Count++
+		b.latitude += latitude
+		b.longitude += longitude
+		b.cluster.Bounds.MinLatitude = math.Min(b.cluster.Bounds.MinLatitude, latitude)
+		b.cluster.Bounds.MinLongitude = math.Min(b.cluster.Bounds.MinLongitude, longitude)
+		b.cluster.Bounds.MaxLatitude = math.Max(b.cluster.Bounds.MaxLatitude, latitude)
+		b.cluster.Bounds.MaxLongitude = math.Max(b.cluster.Bounds.MaxLongitude, longitude)
+	}
+
+	clusters := make([]models.RadarCluster, 0, len(buckets))
+	for _, b := range buckets {
+		cluster := b.cluster
+		cluster.Latitude = b.latitude / float64(cluster.Count)
+		cluster.Longitude = b.longitude / float64(cluster.Count)
+		clusters = append(clusters, cluster)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		if clusters[i].Latitude != clusters[j].Latitude {
+			return clusters[i].Latitude < clusters[j].Latitude
+		}
+		return clusters[i].Longitude < clusters[j].Longitude
+	})
+
+	return clusters, nil
+}
+
+// visible reports whether entry may show up in the viewer's radar queries,
+// returning the user and their privacy settings; callers must hold r.mu
+func (r *RadarRepository) visible(entry models.UserRadar, viewerID int, staleBefore time.Time) (models.User, models.PrivacySettings, bool) {
+	if !entry.IsActive || entry.UpdatedAt.Before(staleBefore) {
+		return models.User{}, models.PrivacySettings{}, false
+	}
+
+	if viewerID != 0 && (entry.UserID == viewerID || r.users.blockedBetween(entry.UserID, viewerID)) {
+		return models.User{}, models.PrivacySettings{}, false
+	}
+
+	// Emulate the JOIN on users, which also hides rows whose user was deleted
+	user, ok := r.users.get(entry.UserID)
+	if !ok {
+		return models.User{}, models.PrivacySettings{}, false
+	}
+
+	settings := r.privacyFor(entry.UserID)
+	if settings.Visibility == models.VisibilityHidden {
+		return models.User{}, models.PrivacySettings{}, false
+	}
+
+	return user, settings, true
+}
+
+func (r *RadarRepository) CountActive(ctx context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	staleBefore := now().Add(-r.presenceTTL)
+	count := 0
+	for _, entry := range r.radar {
+		if entry.IsActive && !entry.UpdatedAt.Before(staleBefore) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *RadarRepository) ExpireStale(ctx context.Context) ([]models.UserRadar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	staleBefore := now().Add(-r.presenceTTL)
+	expired := []models.UserRadar{}
+	for userID, entry := range r.radar {
+		if !entry.IsActive || !entry.UpdatedAt.Before(staleBefore) {
+			continue
+		}
+		entry.IsActive = false
+		r.radar[userID] = entry
+		expired = append(expired, entry)
+	}
+
+	return expired, nil
+}
+
+func (r *RadarRepository) GetPrivacy(ctx context.Context, userID int) (*models.PrivacySettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	settings := r.privacyFor(userID)
+	return &settings, nil
+}
+
+func (r *RadarRepository) UpdatePrivacy(ctx context.Context, userID int, settings models.PrivacySettings) (*models.PrivacySettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings.UpdatedAt = now()
+	r.privacy[userID] = settings
+	return &settings, nil
+}
+
+// privacyFor returns the stored settings or the exact-visibility default;
+// callers must hold r.mu
+func (r *RadarRepository) privacyFor(userID int) models.PrivacySettings {
+	if settings, ok := r.privacy[userID]; ok {
+		return settings
+	}
+	return models.PrivacySettings{Visibility: models.VisibilityExact}
+}
+
+func (r *RadarRepository) History(ctx context.Context, userID int, filter repository.HistoryFilter) ([]models.LocationPoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	points := []models.LocationPoint{}
+	for _, point := range r.history[userID] {
+		if filter.From != nil && point.RecordedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !point.RecordedAt.Before(*filter.To) {
+			continue
+		}
+		points = append(points, point)
+	}
+
+	// Keep the most recent points, like the Postgres LIMIT on a DESC scan
+	if len(points) > filter.Limit {
+		points = points[len(points)-filter.Limit:]
+	}
+
+	return points, nil
+}
@@ -0,0 +1,266 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api-backend/internal/models"
+	"api-backend/internal/repository"
+)
+
+// UserRepository is an in-memory repository.UserRepository for tests and
+// local development
+type UserRepository struct {
+	mu     sync.RWMutex
+	nextID int
+	users  map[int]models.User
+	// blocks maps a blocker to the set of users they blocked
+	blocks map[int]map[int]bool
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{
+		nextID: 1,
+		users:  make(map[int]models.User),
+		blocks: make(map[int]map[int]bool),
+	}
+}
+
+func (r *UserRepository) Create(ctx context.Context, email, passwordHash string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Email == email {
+			return nil, repository.ErrDuplicateEmail
+		}
+	}
+
+	now := now()
+	user := models.User{
+		ID:           r.nextID,
+		Email:        email,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	r.users[user.ID] = user
+	r.nextID++
+
+	return publicUser(user), nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return publicUser(user), nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Email == email {
+			user := u
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *UserRepository) List(ctx context.Context, filter repository.UserFilter) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// before reports whether a sorts ahead of b in the requested direction
+	before := func(a, b models.User) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt) == filter.Ascending
+		}
+		return a.ID != b.ID && (a.ID < b.ID) == filter.Ascending
+	}
+
+	users := []models.User{}
+	for _, u := range r.users {
+		email := strings.ToLower(u.Email)
+		if filter.EmailContains != "" && !strings.Contains(email, strings.ToLower(filter.EmailContains)) {
+			continue
+		}
+		if filter.EmailPrefix != "" && !strings.HasPrefix(email, strings.ToLower(filter.EmailPrefix)) {
+			continue
+		}
+		if filter.CreatedAfter != nil && u.CreatedAt.Before(*filter.CreatedAfter) {
+			continue
+		}
+		if filter.CreatedBefore != nil && !u.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		if filter.After != nil && !before(models.User{ID: filter.After.ID, CreatedAt: filter.After.CreatedAt}, u) {
+			continue
+		}
+		users = append(users, *publicUser(u))
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return before(users[i], users[j])
+	})
+
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, id int, update repository.UserUpdate, expectedUpdatedAt *time.Time) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if expectedUpdatedAt != nil && !user.UpdatedAt.Equal(*expectedUpdatedAt) {
+		return nil, repository.ErrVersionConflict
+	}
+
+	if update.Email != nil {
+		for _, u := range r.users {
+			if u.ID != id && u.Email == *update.Email {
+				return nil, repository.ErrDuplicateEmail
+			}
+		}
+		user.Email = *update.Email
+	}
+	if update.Profile != nil {
+		user.Profile = *update.Profile
+	}
+
+	// Guarantee a new version even when called twice within one microsecond
+	updatedAt := now()
+	if !updatedAt.After(user.UpdatedAt) {
+		updatedAt = user.UpdatedAt.Add(time.Microsecond)
+	}
+	user.UpdatedAt = updatedAt
+	r.users[id] = user
+
+	return publicUser(user), nil
+}
+
+func (r *UserRepository) Exists(ctx context.Context, id int) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.users[id]
+	return ok, nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.users, id)
+
+	// Emulate ON DELETE CASCADE on user_blocks
+	delete(r.blocks, id)
+	for _, blocked := range r.blocks {
+		delete(blocked, id)
+	}
+	return nil
+}
+
+func (r *UserRepository) Block(ctx context.Context, blockerID, blockedID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, blockerExists := r.users[blockerID]
+	_, blockedExists := r.users[blockedID]
+	if !blockerExists || !blockedExists {
+		return repository.ErrNotFound
+	}
+
+	if r.blocks[blockerID] == nil {
+		r.blocks[blockerID] = make(map[int]bool)
+	}
+	r.blocks[blockerID][blockedID] = true
+	return nil
+}
+
+func (r *UserRepository) Unblock(ctx context.Context, blockerID, blockedID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.blocks[blockerID][blockedID] {
+		return repository.ErrNotFound
+	}
+	delete(r.blocks[blockerID], blockedID)
+	return nil
+}
+
+func (r *UserRepository) BlockedUserIDs(ctx context.Context, userID int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := []int{}
+	for blockerID, blocked := range r.blocks {
+		switch {
+		case blockerID == userID:
+			for blockedID := range blocked {
+				ids = append(ids, blockedID)
+			}
+		case blocked[userID]:
+			ids = append(ids, blockerID)
+		}
+	}
+
+	// Deduplicate mutual blocks, like the Postgres UNION
+	sort.Ints(ids)
+	unique := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+// blockedBetween reports whether either user blocked the other
+func (r *UserRepository) blockedBetween(a, b int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.blocks[a][b] || r.blocks[b][a]
+}
+
+// get returns the stored user; sibling repositories use it to emulate joins
+func (r *UserRepository) get(id int) (models.User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	return user, ok
+}
+
+// now returns the current time at the microsecond precision Postgres stores
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// publicUser mirrors the Postgres repository, which only selects the
+// password hash in GetByEmail
+func publicUser(u models.User) *models.User {
+	u.PasswordHash = ""
+	return &u
+}
@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"api-backend/internal/database"
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+	"api-backend/internal/repository"
+)
+
+const geofenceColumns = `
+	id, owner_id, name, fence_type,
+	ST_Y(center::geometry), ST_X(center::geometry), radius_meters,
+	ST_AsGeoJSON(area::geometry),
+	created_at, updated_at
+`
+
+// geofenceValues builds a circle's center from $4/$5 and a polygon's area
+// from the GeoJSON in $7; the unused shape's arguments are NULL
+const geofenceValues = `
+	CASE WHEN $4::float8 IS NULL THEN NULL ELSE ST_SetSRID(ST_MakePoint($4, $5::float8), 4326)::geography END,
+	$6::float8,
+	CASE WHEN $7::text IS NULL THEN NULL ELSE ST_SetSRID(ST_GeomFromGeoJSON($7), 4326)::geography END
+`
+
+type GeofenceRepository struct {
+	db *database.Database
+}
+
+func NewGeofenceRepository(db *database.Database) *GeofenceRepository {
+	return &GeofenceRepository{db: db}
+}
+
+func (r *GeofenceRepository) Create(ctx context.Context, fence models.Geofence) (*models.Geofence, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	args, err := geofenceArgs(fence)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO geofences (owner_id, name, fence_type, center, radius_meters, area)
+		VALUES ($1, $2, $3, ` + geofenceValues + `)
+		RETURNING ` + geofenceColumns
+
+	return scanGeofence(r.db.DB.QueryRowContext(ctx, query, args...))
+}
+
+func (r *GeofenceRepository) GetByID(ctx context.Context, id int) (*models.Geofence, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	fence, err := scanGeofence(r.db.DB.QueryRowContext(ctx, "SELECT "+geofenceColumns+" FROM geofences WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	return fence, err
+}
+
+func (r *GeofenceRepository) ListByOwner(ctx context.Context, ownerID int) ([]models.Geofence, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.DB.QueryContext(ctx, "SELECT "+geofenceColumns+" FROM geofences WHERE owner_id = $1 ORDER BY id", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fences := []models.Geofence{}
+	for rows.Next() {
+		fence, err := scanGeofence(rows)
+		if err != nil {
+			return nil, err
+		}
+		fences = append(fences, *fence)
+	}
+
+	return fences, rows.Err()
+}
+
+func (r *GeofenceRepository) Update(ctx context.Context, fence models.Geofence) (*models.Geofence, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	args, err := geofenceArgs(fence)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, fence.ID)
+
+	query := `
+		UPDATE geofences
+		SET (owner_id, name, fence_type, center, radius_meters, area) = ($1, $2, $3, ` + geofenceValues + `),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8
+		RETURNING ` + geofenceColumns
+
+	updated, err := scanGeofence(r.db.DB.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	return updated, err
+}
+
+func (r *GeofenceRepository) Delete(ctx context.Context, id int) error {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.DB.ExecContext(ctx, "DELETE FROM geofences WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// RecordTransitions evaluates ST_DWithin (circles) and ST_Within (polygons)
+// for both positions and inserts an event for every fence whose state differs
+func (r *GeofenceRepository) RecordTransitions(ctx context.Context, userID int, previous *geo.Point, current geo.Point) ([]models.GeofenceEvent, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	var previousLon, previousLat interface{}
+	if previous != nil {
+		previousLon, previousLat = previous.Longitude, previous.Latitude
+	}
+
+	query := `
+		WITH points AS (
+			SELECT
+				ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography AS current_point,
+				CASE WHEN $4::float8 IS NULL THEN NULL
+					ELSE ST_SetSRID(ST_MakePoint($4, $5::float8), 4326)::geography END AS previous_point
+		), states AS (
+			SELECT
+				g.id,
+				CASE WHEN g.fence_type = 'circle'
+					THEN ST_DWithin(p.current_point, g.center, g.radius_meters)
+					ELSE ST_Within(p.current_point::geometry, g.area::geometry) END AS inside_now,
+				COALESCE(CASE WHEN g.fence_type = 'circle'
+					THEN ST_DWithin(p.previous_point, g.center, g.radius_meters)
+					ELSE ST_Within(p.previous_point::geometry, g.area::geometry) END, false) AS inside_before
+			FROM geofences g, points p
+			WHERE g.owner_id = $1
+		)
+		INSERT INTO geofence_events (geofence_id, user_id, event_type, location)
+		SELECT s.id, $1, CASE WHEN s.inside_now THEN 'enter' ELSE 'exit' END, p.current_point
+		FROM states s, points p
+		WHERE s.inside_now <> s.inside_before
+		RETURNING id, geofence_id, user_id, event_type, ST_Y(location::geometry), ST_X(location::geometry), occurred_at
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, current.Longitude, current.Latitude, previousLon, previousLat)
+	if err != nil {
+		return nil, err
+	}
+	return scanGeofenceEvents(rows)
+}
+
+func (r *GeofenceRepository) ListEvents(ctx context.Context, filter repository.GeofenceEventFilter) ([]models.GeofenceEvent, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, geofence_id, user_id, event_type, ST_Y(location::geometry), ST_X(location::geometry), occurred_at
+		FROM geofence_events
+		WHERE ($1 = 0 OR user_id = $1)
+		AND ($2 = 0 OR geofence_id = $2)
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, filter.UserID, filter.GeofenceID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanGeofenceEvents(rows)
+}
+
+func geofenceArgs(fence models.Geofence) ([]interface{}, error) {
+	var longitude, latitude, radius, polygon interface{}
+	if fence.Latitude != nil && fence.Longitude != nil {
+		longitude, latitude = *fence.Longitude, *fence.Latitude
+	}
+	if fence.RadiusMeters != nil {
+		radius = *fence.RadiusMeters
+	}
+	if fence.Polygon != nil {
+		encoded, err := json.Marshal(fence.Polygon)
+		if err != nil {
+			return nil, err
+		}
+		polygon = string(encoded)
+	}
+
+	return []interface{}{fence.OwnerID, fence.Name, fence.Type, longitude, latitude, radius, polygon}, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGeofence(row rowScanner) (*models.Geofence, error) {
+	var fence models.Geofence
+	var latitude, longitude, radius sql.NullFloat64
+	var area sql.NullString
+
+	err := row.Scan(
+		&fence.ID, &fence.OwnerID, &fence.Name, &fence.Type,
+		&latitude, &longitude, &radius,
+		&area,
+		&fence.CreatedAt, &fence.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if latitude.Valid && longitude.Valid {
+		fence.Latitude = &latitude.Float64
+		fence.Longitude = &longitude.Float64
+	}
+	if radius.Valid {
+		fence.RadiusMeters = &radius.Float64
+	}
+	if area.Valid {
+		var polygon models.GeoJSONPolygon
+		if err := json.Unmarshal([]byte(area.String), &polygon); err != nil {
+			return nil, err
+		}
+		fence.Polygon = &polygon
+	}
+
+	return &fence, nil
+}
+
+func scanGeofenceEvents(rows *sql.Rows) ([]models.GeofenceEvent, error) {
+	defer rows.Close()
+
+	events := []models.GeofenceEvent{}
+	for rows.Next() {
+		var event models.GeofenceEvent
+		if err := rows.Scan(&event.ID, &event.GeofenceID, &event.UserID, &event.Type, &event.Latitude, &event.Longitude, &event.OccurredAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
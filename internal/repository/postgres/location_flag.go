@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+
+	"api-backend/internal/database"
+	"api-backend/internal/models"
+)
+
+type LocationFlagRepository struct {
+	db *database.Database
+}
+
+func NewLocationFlagRepository(db *database.Database) *LocationFlagRepository {
+	return &LocationFlagRepository{db: db}
+}
+
+func (r *LocationFlagRepository) Create(ctx context.Context, flag models.LocationFlag) (*models.LocationFlag, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO location_flags (user_id, from_location, to_location, distance_meters, elapsed_seconds, speed_kmh, action)
+		VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), ST_SetSRID(ST_MakePoint($4, $5), 4326), $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	err := r.db.DB.QueryRowContext(ctx, query,
+		flag.UserID,
+		flag.FromLongitude,
+		flag.FromLatitude,
+		flag.ToLongitude,
+		flag.ToLatitude,
+		flag.DistanceMeters,
+		flag.ElapsedSeconds,
+		flag.SpeedKmh,
+		flag.Action,
+	).Scan(&flag.ID, &flag.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flag, nil
+}
+
+func (r *LocationFlagRepository) ListByUser(ctx context.Context, userID int, limit int) ([]models.LocationFlag, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			ST_Y(from_location::geometry),
+			ST_X(from_location::geometry),
+			ST_Y(to_location::geometry),
+			ST_X(to_location::geometry),
+			distance_meters,
+			elapsed_seconds,
+			speed_kmh,
+			action,
+			created_at
+		FROM location_flags
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []models.LocationFlag{}
+	for rows.Next() {
+		var flag models.LocationFlag
+		if err := rows.Scan(&flag.ID, &flag.UserID, &flag.FromLatitude, &flag.FromLongitude, &flag.ToLatitude, &flag.ToLongitude,
+			&flag.DistanceMeters, &flag.ElapsedSeconds, &flag.SpeedKmh, &flag.Action, &flag.CreatedAt); err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, rows.Err()
+}
@@ -0,0 +1,633 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"api-backend/internal/database"
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+	"api-backend/internal/privacy"
+	"api-backend/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTestDB connects to DATABASE_URL and resets the schema's data. These
+// tests exercise the real SQL and PostGIS functions, so they are skipped when
+// no database is configured (run them with `make test`).
+func setupTestDB(t *testing.T) *database.Database {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("DATABASE_URL not set, skipping Postgres integration test")
+	}
+
+	db, err := database.New(databaseURL, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Clean up test data
+	db.DB.Exec("DELETE FROM geofence_events")
+	db.DB.Exec("DELETE FROM geofences")
+	db.DB.Exec("DELETE FROM location_history")
+	db.DB.Exec("DELETE FROM location_flags")
+	db.DB.Exec("DELETE FROM user_blocks")
+	db.DB.Exec("DELETE FROM user_radar_privacy")
+	db.DB.Exec("DELETE FROM user_radar")
+	db.DB.Exec("DELETE FROM users")
+
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestUserRepository_CreateAndGet(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	created, err := users.Create(ctx, "pg@example.com", "hash")
+	assert.NoError(t, err)
+
+	_, err = users.Create(ctx, "pg@example.com", "hash")
+	assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+
+	byEmail, err := users.GetByEmail(ctx, "pg@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, byEmail.ID)
+	assert.Equal(t, "hash", byEmail.PasswordHash)
+
+	email := "pg2@example.com"
+	updated, err := users.Update(ctx, created.ID, repository.UserUpdate{Email: &email}, &created.UpdatedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, email, updated.Email)
+
+	_, err = users.Update(ctx, created.ID, repository.UserUpdate{Email: &email}, &created.UpdatedAt)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	assert.NoError(t, users.Delete(ctx, created.ID))
+	_, err = users.GetByID(ctx, created.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, users.Delete(ctx, created.ID), repository.ErrNotFound)
+}
+
+func TestUserRepository_ListKeyset(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@test.org"} {
+		_, err := users.Create(ctx, email, "")
+		assert.NoError(t, err)
+	}
+
+	page, err := users.List(ctx, repository.UserFilter{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page, 2)
+
+	last := page[len(page)-1]
+	rest, err := users.List(ctx, repository.UserFilter{
+		Limit: 2,
+		After: &repository.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rest, 1)
+	assert.NotEqual(t, last.ID, rest[0].ID)
+
+	filtered, err := users.List(ctx, repository.UserFilter{EmailContains: "EXAMPLE", Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, filtered, 2)
+
+	filtered, err = users.List(ctx, repository.UserFilter{EmailPrefix: "c@", Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, filtered, 1)
+}
+
+func TestUserRepository_Blocks(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	alice, _ := users.Create(ctx, "alice@example.com", "")
+	bob, _ := users.Create(ctx, "bob@example.com", "")
+	_, err := radar.UpsertLocation(ctx, alice.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+	_, err = radar.UpsertLocation(ctx, bob.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10, ViewerID: alice.ID})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1, "the viewer is excluded")
+	assert.Equal(t, bob.ID, nearby[0].UserID)
+
+	assert.NoError(t, users.Block(ctx, bob.ID, alice.ID))
+	assert.NoError(t, users.Block(ctx, bob.ID, alice.ID), "blocking twice is not an error")
+	assert.ErrorIs(t, users.Block(ctx, bob.ID, 999999), repository.ErrNotFound)
+
+	ids, err := users.BlockedUserIDs(ctx, alice.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{bob.ID}, ids)
+
+	nearby, err = radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10, ViewerID: alice.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, nearby)
+
+	assert.NoError(t, users.Unblock(ctx, bob.ID, alice.ID))
+	assert.ErrorIs(t, users.Unblock(ctx, bob.ID, alice.ID), repository.ErrNotFound)
+}
+
+func TestRadarRepository_UpsertAndFindNearby(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	berlin, _ := users.Create(ctx, "berlin@example.com", "")
+	munich, _ := users.Create(ctx, "munich@example.com", "")
+	hamburg, _ := users.Create(ctx, "hamburg@example.com", "")
+
+	first, err := radar.UpsertLocation(ctx, berlin.ID, models.LocationPoint{Latitude: 48.8566, Longitude: 2.3522, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	updated, err := radar.UpsertLocation(ctx, berlin.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, updated.ID)
+	assert.InDelta(t, 52.5200, updated.Latitude, 0.0001)
+
+	trail, err := radar.History(ctx, berlin.ID, repository.HistoryFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, trail, 2)
+	assert.InDelta(t, 48.8566, trail[0].Latitude, 0.0001)
+
+	_, err = radar.UpsertLocation(ctx, munich.ID, models.LocationPoint{Latitude: 48.1351, Longitude: 11.5820, IsActive: true}, nil)
+	assert.NoError(t, err)
+	_, err = radar.UpsertLocation(ctx, hamburg.ID, models.LocationPoint{Latitude: 53.5511, Longitude: 9.9937, IsActive: false}, nil)
+	assert.NoError(t, err)
+
+	// Munich is ~504 km away and Hamburg is inactive
+	nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 300})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1)
+	assert.Equal(t, berlin.ID, nearby[0].UserID)
+
+	nearby, err = radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 600})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 2)
+	assert.Less(t, nearby[0].DistanceKm, nearby[1].DistanceKm)
+	assert.Equal(t, models.VisibilityExact, nearby[0].Visibility)
+
+	// Without a radius the KNN bound still returns the closest first
+	nearby, err = radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, Nearest: true, Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1)
+	assert.Equal(t, munich.ID, nearby[0].UserID)
+
+	// A bounding box around Bavaria leaves out Berlin
+	bavaria := geo.BoundingBox(geo.Point{Latitude: 47.3, Longitude: 9.0}, geo.Point{Latitude: 50.5, Longitude: 13.8})
+	nearby, err = radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, Area: bavaria})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1)
+	assert.Equal(t, munich.ID, nearby[0].UserID)
+}
+
+func TestRadarRepository_FixMetadata(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	precise, _ := users.Create(ctx, "precise@example.com", "")
+	unknown, _ := users.Create(ctx, "unknown@example.com", "")
+
+	accuracy, altitude, speed, course := 8.0, 34.5, 1.4, 90.0
+	deviceTime := time.Now().Add(-time.Second).UTC().Truncate(time.Microsecond)
+	stored, err := radar.UpsertLocation(ctx, precise.ID, models.LocationPoint{
+		Latitude:  52.5200,
+		Longitude: 13.4050,
+		IsActive:  true,
+		FixMetadata: models.FixMetadata{
+			AccuracyMeters: &accuracy,
+			AltitudeMeters: &altitude,
+			SpeedMps:       &speed,
+			CourseDegrees:  &course,
+		},
+		DeviceTimestamp: &deviceTime,
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, accuracy, *stored.AccuracyMeters)
+	assert.Equal(t, course, *stored.CourseDegrees)
+	assert.True(t, stored.DeviceTimestamp.Equal(deviceTime))
+
+	_, err = radar.UpsertLocation(ctx, unknown.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	trail, err := radar.History(ctx, precise.ID, repository.HistoryFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, trail, 1)
+	assert.Equal(t, speed, *trail[0].SpeedMps)
+
+	nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10, MaxAccuracyMeters: 10})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1)
+	assert.Equal(t, precise.ID, nearby[0].UserID)
+}
+
+func TestRadarRepository_UpsertLocationChecksLockedPosition(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	user, _ := users.Create(ctx, "checked@example.com", "")
+	_, err := radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	// An error from the check aborts the write
+	rejected := errors.New("rejected")
+	_, err = radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 35.6762, Longitude: 139.6503, IsActive: true},
+		func(*models.UserRadar) error { return rejected })
+	assert.ErrorIs(t, err, rejected)
+	current, err := radar.GetLocation(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.InDelta(t, 52.5200, current.Latitude, 0.0001)
+
+	// A concurrent write waits for the first one and is checked against the
+	// position it stored, not the one both started from
+	entered, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 52.3906, Longitude: 13.0645, IsActive: true},
+			func(*models.UserRadar) error {
+				close(entered)
+				<-release
+				return nil
+			})
+		done <- err
+	}()
+	<-entered
+
+	seen := make(chan float64, 1)
+	go func() {
+		_, err := radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 53.5511, Longitude: 9.9937, IsActive: true},
+			func(current *models.UserRadar) error {
+				seen <- current.Latitude
+				return nil
+			})
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-done)
+	assert.InDelta(t, 52.3906, <-seen, 0.0001)
+}
+
+func TestRadarRepository_AppendLocations(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	user, _ := users.Create(ctx, "offline@example.com", "")
+	start := time.Now().Add(-10 * time.Minute).UTC()
+	fix := func(minutes int, latitude float64) models.LocationPoint {
+		return models.LocationPoint{Latitude: latitude, Longitude: 13.4050, IsActive: true, RecordedAt: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	current, accepted, err := radar.AppendLocations(ctx, user.ID, []models.LocationPoint{fix(2, 52.5220), fix(0, 52.5200), fix(1, 52.5210)}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, accepted)
+	assert.InDelta(t, 52.5220, current.Latitude, 0.0001)
+	assert.WithinDuration(t, start.Add(2*time.Minute), current.UpdatedAt, time.Millisecond)
+
+	// A retry, including sub-microsecond timestamps, is ignored
+	current, accepted, err = radar.AppendLocations(ctx, user.ID, []models.LocationPoint{fix(2, 52.5220), fix(-1, 52.5190)}, nil)
+	assert.NoError(t, err)
+	assert.Zero(t, accepted)
+	assert.InDelta(t, 52.5220, current.Latitude, 0.0001)
+
+	trail, err := radar.History(ctx, user.ID, repository.HistoryFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, trail, 3)
+	assert.InDelta(t, 52.5200, trail[0].Latitude, 0.0001)
+}
+
+func TestRadarRepository_Privacy(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	fuzzed, _ := users.Create(ctx, "fuzzed@example.com", "")
+	hidden, _ := users.Create(ctx, "hidden@example.com", "")
+
+	settings, err := radar.GetPrivacy(ctx, fuzzed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VisibilityExact, settings.Visibility)
+
+	_, err = radar.UpdatePrivacy(ctx, fuzzed.ID, models.PrivacySettings{Visibility: models.VisibilityFuzzed, FuzzMeters: 500})
+	assert.NoError(t, err)
+	_, err = radar.UpdatePrivacy(ctx, hidden.ID, models.PrivacySettings{Visibility: models.VisibilityHidden})
+	assert.NoError(t, err)
+
+	_, err = radar.UpsertLocation(ctx, fuzzed.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+	_, err = radar.UpsertLocation(ctx, hidden.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1)
+	assert.Equal(t, fuzzed.ID, nearby[0].UserID)
+	assert.Equal(t, models.VisibilityFuzzed, nearby[0].Visibility)
+	assert.Equal(t, 500, nearby[0].FuzzMeters)
+}
+
+func TestRadarRepository_FindNearbyFiltersMaskedDistance(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	exact, _ := users.Create(ctx, "exact@example.com", "")
+	fuzzed, _ := users.Create(ctx, "fuzzed@example.com", "")
+	distanceOnly, _ := users.Create(ctx, "distance@example.com", "")
+
+	_, err := radar.UpdatePrivacy(ctx, fuzzed.ID, models.PrivacySettings{Visibility: models.VisibilityFuzzed, FuzzMeters: privacy.DefaultFuzzMeters})
+	assert.NoError(t, err)
+	_, err = radar.UpdatePrivacy(ctx, distanceOnly.ID, models.PrivacySettings{Visibility: models.VisibilityDistanceOnly})
+	assert.NoError(t, err)
+	for _, user := range []*models.User{exact, fuzzed, distanceOnly} {
+		_, err = radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 52.5350, Longitude: 13.4300, IsActive: true}, nil)
+		assert.NoError(t, err)
+	}
+
+	// All are 2.4 km away, but the fuzzed position is 2.7 km away and the
+	// distance-only user is reported at 3 km
+	find := func(radiusKm float64) []int {
+		nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: radiusKm})
+		assert.NoError(t, err)
+		var ids []int
+		for _, user := range nearby {
+			ids = append(ids, user.UserID)
+		}
+		return ids
+	}
+	assert.Empty(t, find(2.3))
+	assert.Equal(t, []int{exact.ID}, find(2.5))
+	assert.Equal(t, []int{exact.ID, fuzzed.ID}, find(2.8))
+	assert.Equal(t, []int{exact.ID, fuzzed.ID, distanceOnly.ID}, find(3))
+}
+
+func TestRadarRepository_FindNearbyWidensByOwnGrid(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	coarse, _ := users.Create(ctx, "coarse@example.com", "")
+	_, err := radar.UpdatePrivacy(ctx, coarse.ID, models.PrivacySettings{Visibility: models.VisibilityFuzzed, FuzzMeters: privacy.MaxFuzzMeters})
+	assert.NoError(t, err)
+	_, err = radar.UpsertLocation(ctx, coarse.ID, models.LocationPoint{Latitude: 52.5266, Longitude: 13.8961, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	// The cell center is 30 km from the exact position, far more than the
+	// default grid could move it
+	latitude, longitude := privacy.Fuzz(52.5266, 13.8961, privacy.MaxFuzzMeters)
+	nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: latitude, Longitude: longitude, RadiusKm: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, nearby, 1) {
+		assert.Equal(t, coarse.ID, nearby[0].UserID)
+	}
+}
+
+func TestRadarRepository_FindNearbyNearestPages(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	visibilities := []string{models.VisibilityExact, models.VisibilityFuzzed, models.VisibilityDistanceOnly}
+	for i := 0; i < 9; i++ {
+		user, _ := users.Create(ctx, fmt.Sprintf("nearest%d@example.com", i), "")
+		_, err := radar.UpdatePrivacy(ctx, user.ID, models.PrivacySettings{Visibility: visibilities[i%3], FuzzMeters: 5000})
+		assert.NoError(t, err)
+		_, err = radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 52.52 + 0.01*float64(i), Longitude: 13.405, IsActive: true}, nil)
+		assert.NoError(t, err)
+	}
+
+	ids := func(nearby []models.NearbyUser) []int {
+		var ids []int
+		for _, user := range nearby {
+			ids = append(ids, user.UserID)
+		}
+		return ids
+	}
+	all, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.52, Longitude: 13.405})
+	assert.NoError(t, err)
+
+	// Pages bounded by the nearest users match a sort of every user
+	var paged []int
+	for offset := 0; offset < len(all); offset += 2 {
+		page, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.52, Longitude: 13.405, Nearest: true, Limit: 2, Offset: offset})
+		assert.NoError(t, err)
+		paged = append(paged, ids(page)...)
+	}
+	assert.Equal(t, ids(all), paged)
+}
+
+func TestRadarRepository_FindNearbyMatchesMaskedArea(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	fuzzed, _ := users.Create(ctx, "fuzzed@example.com", "")
+	_, err := radar.UpdatePrivacy(ctx, fuzzed.ID, models.PrivacySettings{Visibility: models.VisibilityFuzzed, FuzzMeters: 5000})
+	assert.NoError(t, err)
+	_, err = radar.UpsertLocation(ctx, fuzzed.ID, models.LocationPoint{Latitude: 52.5210, Longitude: 13.4060, IsActive: true}, nil)
+	assert.NoError(t, err)
+
+	around := func(latitude, longitude float64) []models.NearbyUser {
+		min := geo.Point{Latitude: latitude - 0.0001, Longitude: longitude - 0.0001}
+		max := geo.Point{Latitude: latitude + 0.0001, Longitude: longitude + 0.0001}
+		nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: latitude, Longitude: longitude, Area: geo.BoundingBox(min, max)})
+		assert.NoError(t, err)
+		return nearby
+	}
+
+	assert.Empty(t, around(52.5210, 13.4060), "the exact position is not matched")
+	latitude, longitude := privacy.Fuzz(52.5210, 13.4060, 5000)
+	assert.Len(t, around(latitude, longitude), 1)
+}
+
+func TestRadarRepository_Clusters(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	exact, _ := users.Create(ctx, "exact@example.com", "")
+	fuzzed, _ := users.Create(ctx, "fuzzed@example.com", "")
+	distanceOnly, _ := users.Create(ctx, "distance@example.com", "")
+
+	_, err := radar.UpdatePrivacy(ctx, fuzzed.ID, models.PrivacySettings{Visibility: models.VisibilityFuzzed, FuzzMeters: 5000})
+	assert.NoError(t, err)
+	_, err = radar.UpdatePrivacy(ctx, distanceOnly.ID, models.PrivacySettings{Visibility: models.VisibilityDistanceOnly})
+	assert.NoError(t, err)
+	for _, user := range []*models.User{exact, fuzzed, distanceOnly} {
+		_, err = radar.UpsertLocation(ctx, user.ID, models.LocationPoint{Latitude: 52.5210, Longitude: 13.4060, IsActive: true}, nil)
+		assert.NoError(t, err)
+	}
+
+	// Small cells separate the exact position from the fuzzed one, which
+	// radar_fuzz must place exactly where privacy.Fuzz does
+	clusters, err := radar.Clusters(ctx, repository.ClusterFilter{
+		Min:         geo.Point{Latitude: 52.3, Longitude: 13.0},
+		Max:         geo.Point{Latitude: 52.6, Longitude: 13.6},
+		CellDegrees: 0.001,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, clusters, 2)
+
+	latitude, longitude := privacy.Fuzz(52.5210, 13.4060, 5000)
+	var fuzzedCluster models.RadarCluster
+	for _, cluster := range clusters {
+		assert.Equal(t, 1, cluster.Count)
+		if math.Abs(cluster.Latitude-52.5210) > 0.000001 {
+			fuzzedCluster = cluster
+		}
+	}
+	assert.InDelta(t, latitude, fuzzedCluster.Latitude, 0.0000001)
+	assert.InDelta(t, longitude, fuzzedCluster.Longitude, 0.0000001)
+
+	// Large cells merge them and the viewer is left out
+	clusters, err = radar.Clusters(ctx, repository.ClusterFilter{
+		Min:         geo.Point{Latitude: 52.3, Longitude: 13.0},
+		Max:         geo.Point{Latitude: 52.6, Longitude: 13.6},
+		CellDegrees: 1,
+		ViewerID:    exact.ID,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, clusters, 1)
+	assert.Equal(t, 1, clusters[0].Count)
+}
+
+func TestRadarRepository_ExpireStale(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	radar := NewRadarRepository(db, time.Hour)
+	ctx := context.Background()
+
+	fresh, _ := users.Create(ctx, "fresh@example.com", "")
+	stale, _ := users.Create(ctx, "stale@example.com", "")
+
+	_, err := radar.UpsertLocation(ctx, fresh.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+	_, err = radar.UpsertLocation(ctx, stale.ID, models.LocationPoint{Latitude: 52.5200, Longitude: 13.4050, IsActive: true}, nil)
+	assert.NoError(t, err)
+	_, err = db.DB.Exec("UPDATE user_radar SET updated_at = updated_at - interval '2 hours' WHERE user_id = $1", stale.ID)
+	assert.NoError(t, err)
+
+	nearby, err := radar.FindNearby(ctx, repository.NearbyFilter{Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10})
+	assert.NoError(t, err)
+	assert.Len(t, nearby, 1, "stale rows are excluded before the janitor runs")
+	assert.Equal(t, fresh.ID, nearby[0].UserID)
+
+	expired, err := radar.ExpireStale(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+	assert.Equal(t, stale.ID, expired[0].UserID)
+	assert.False(t, expired[0].IsActive)
+
+	expired, err = radar.ExpireStale(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+}
+
+func TestLocationFlagRepository_CreateAndList(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	flags := NewLocationFlagRepository(db)
+	ctx := context.Background()
+
+	user, _ := users.Create(ctx, "spoofer@example.com", "")
+
+	for _, action := range []string{models.FlagActionFlagged, models.FlagActionRejected} {
+		created, err := flags.Create(ctx, models.LocationFlag{
+			UserID:         user.ID,
+			FromLatitude:   52.5200,
+			FromLongitude:  13.4050,
+			ToLatitude:     35.6762,
+			ToLongitude:    139.6503,
+			DistanceMeters: 8900000,
+			ElapsedSeconds: 1,
+			SpeedKmh:       32040000,
+			Action:         action,
+		})
+		assert.NoError(t, err)
+		assert.NotZero(t, created.ID)
+	}
+
+	listed, err := flags.ListByUser(ctx, user.ID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 2)
+	assert.Equal(t, models.FlagActionRejected, listed[0].Action)
+	assert.InDelta(t, 35.6762, listed[0].ToLatitude, 0.0001)
+	assert.InDelta(t, 13.4050, listed[0].FromLongitude, 0.0001)
+}
+
+func TestGeofenceRepository_RecordTransitions(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	geofences := NewGeofenceRepository(db)
+	ctx := context.Background()
+
+	owner, _ := users.Create(ctx, "fences@example.com", "")
+
+	lat, lon, radius := 52.5200, 13.4050, 500.0
+	circle, err := geofences.Create(ctx, models.Geofence{
+		OwnerID: owner.ID, Name: "Home", Type: models.GeofenceCircle,
+		Latitude: &lat, Longitude: &lon, RadiusMeters: &radius,
+	})
+	assert.NoError(t, err)
+	assert.InDelta(t, lat, *circle.Latitude, 0.0001)
+
+	polygon, err := geofences.Create(ctx, models.Geofence{
+		OwnerID: owner.ID, Name: "Berlin", Type: models.GeofencePolygon,
+		Polygon: &models.GeoJSONPolygon{
+			Type:        "Polygon",
+			Coordinates: [][][]float64{{{13.3, 52.4}, {13.5, 52.4}, {13.5, 52.6}, {13.3, 52.6}, {13.3, 52.4}}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Polygon", polygon.Polygon.Type)
+
+	home := geo.Point{Latitude: 52.5201, Longitude: 13.4051}
+	suburb := geo.Point{Latitude: 52.4500, Longitude: 13.3500}
+
+	events, err := geofences.RecordTransitions(ctx, owner.ID, nil, home)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	events, err = geofences.RecordTransitions(ctx, owner.ID, &home, suburb)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, circle.ID, events[0].GeofenceID)
+	assert.Equal(t, "exit", events[0].Type)
+
+	listed, err := geofences.ListEvents(ctx, repository.GeofenceEventFilter{GeofenceID: circle.ID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, listed, 2)
+}
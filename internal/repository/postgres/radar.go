@@ -0,0 +1,669 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"api-backend/internal/database"
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+	"api-backend/internal/privacy"
+	"api-backend/internal/repository"
+)
+
+// RadarRepository treats positions not updated within presenceTTL as stale:
+// they are left out of nearby results and deactivated by ExpireStale
+type RadarRepository struct {
+	db          *database.Database
+	presenceTTL time.Duration
+}
+
+func NewRadarRepository(db *database.Database, presenceTTL time.Duration) *RadarRepository {
+	return &RadarRepository{db: db, presenceTTL: presenceTTL}
+}
+
+// radarColumns are the user_radar columns scanRadar reads, in order
+const radarColumns = `id, user_id, ST_Y(location::geometry) as latitude, ST_X(location::geometry) as longitude, is_active,
+	accuracy_meters, altitude_meters, speed_mps, course_degrees, device_timestamp, created_at, updated_at`
+
+// UpsertLocation creates or replaces a user's current position and appends
+// it to the location history. fix.RecordedAt is ignored: live updates are
+// stamped with the server clock. The check runs in the same transaction as
+// the write, with the user locked.
+func (r *RadarRepository) UpsertLocation(ctx context.Context, userID int, fix models.LocationPoint, check repository.LocationCheck) (*models.UserRadar, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockLocation(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if check != nil {
+		if err := check(current); err != nil {
+			return nil, err
+		}
+	}
+
+	// Upsert location using ON CONFLICT; the history insert shares the
+	// statement so both writes commit or fail together
+	query := `
+		WITH upserted AS (
+			INSERT INTO user_radar (user_id, location, is_active, accuracy_meters, altitude_meters, speed_mps, course_degrees, device_timestamp, updated_at)
+			VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP)
+			ON CONFLICT (user_id)
+			DO UPDATE SET
+				location = EXCLUDED.location,
+				is_active = EXCLUDED.is_active,
+				accuracy_meters = EXCLUDED.accuracy_meters,
+				altitude_meters = EXCLUDED.altitude_meters,
+				speed_mps = EXCLUDED.speed_mps,
+				course_degrees = EXCLUDED.course_degrees,
+				device_timestamp = EXCLUDED.device_timestamp,
+				updated_at = CURRENT_TIMESTAMP
+			RETURNING *
+		), history AS (
+			INSERT INTO location_history (user_id, location, is_active, accuracy_meters, altitude_meters, speed_mps, course_degrees, device_timestamp, recorded_at)
+			SELECT user_id, location, is_active, accuracy_meters, altitude_meters, speed_mps, course_degrees, device_timestamp, updated_at FROM upserted
+		)
+		SELECT ` + radarColumns + `
+		FROM upserted
+	`
+
+	radar, err := scanRadar(tx.QueryRowContext(ctx,
+		query,
+		userID,
+		fix.Longitude,
+		fix.Latitude,
+		fix.IsActive,
+		fix.AccuracyMeters,
+		fix.AltitudeMeters,
+		fix.SpeedMps,
+		fix.CourseDegrees,
+		utcOrNil(fix.DeviceTimestamp),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return radar, nil
+}
+
+// AppendLocations locks the user while it filters and writes the batch, so
+// concurrent uploads apply one after the other
+func (r *RadarRepository) AppendLocations(ctx context.Context, userID int, fixes []models.LocationPoint, filter repository.LocationFilter) (*models.UserRadar, int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockLocation(ctx, tx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if filter != nil {
+		fixes = filter(current, fixes)
+	}
+
+	// Timestamps are stored with microsecond precision; truncating first
+	// makes a retried upload compare equal and get ignored
+	accepted := make([]models.LocationPoint, 0, len(fixes))
+	for _, fix := range fixes {
+		fix.RecordedAt = fix.RecordedAt.UTC().Truncate(time.Microsecond)
+		if current != nil && !fix.RecordedAt.After(current.UpdatedAt) {
+			continue
+		}
+		accepted = append(accepted, fix)
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].RecordedAt.Before(accepted[j].RecordedAt)
+	})
+
+	if len(accepted) > 0 {
+		args := []interface{}{userID}
+		arg := func(value interface{}) string {
+			args = append(args, value)
+			return fmt.Sprintf("$%d", len(args))
+		}
+
+		values := make([]string, 0, len(accepted))
+		for _, fix := range accepted {
+			values = append(values, fmt.Sprintf("($1, ST_SetSRID(ST_MakePoint(%s, %s), 4326), %s, %s, %s, %s, %s, %s, %s)",
+				arg(fix.Longitude), arg(fix.Latitude), arg(fix.IsActive),
+				arg(fix.AccuracyMeters), arg(fix.AltitudeMeters), arg(fix.SpeedMps), arg(fix.CourseDegrees),
+				arg(utcOrNil(fix.DeviceTimestamp)), arg(fix.RecordedAt)))
+		}
+		history := `INSERT INTO location_history (user_id, location, is_active, accuracy_meters, altitude_meters, speed_mps, course_degrees, device_timestamp, recorded_at)
+			VALUES ` + strings.Join(values, ", ")
+		if _, err := tx.ExecContext(ctx, history, args...); err != nil {
+			return nil, 0, err
+		}
+
+		// Older fixes were dropped above while the user is locked; the guard
+		// keeps the current position from ever moving back regardless
+		newest := accepted[len(accepted)-1]
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO user_radar (user_id, location, is_active, accuracy_meters, altitude_meters, speed_mps, course_degrees, device_timestamp, updated_at)
+			VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (user_id)
+			DO UPDATE SET
+				location = EXCLUDED.location,
+				is_active = EXCLUDED.is_active,
+				accuracy_meters = EXCLUDED.accuracy_meters,
+				altitude_meters = EXCLUDED.altitude_meters,
+				speed_mps = EXCLUDED.speed_mps,
+				course_degrees = EXCLUDED.course_degrees,
+				device_timestamp = EXCLUDED.device_timestamp,
+				updated_at = EXCLUDED.updated_at
+			WHERE user_radar.updated_at < EXCLUDED.updated_at
+		`, userID, newest.Longitude, newest.Latitude, newest.IsActive,
+			newest.AccuracyMeters, newest.AltitudeMeters, newest.SpeedMps, newest.CourseDegrees,
+			utcOrNil(newest.DeviceTimestamp), newest.RecordedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	radar, err := scanRadar(tx.QueryRowContext(ctx, "SELECT "+radarColumns+" FROM user_radar WHERE user_id = $1", userID))
+	if err == sql.ErrNoRows {
+		return nil, 0, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+
+	return radar, len(accepted), nil
+}
+
+// lockLocation serializes location writes for a user within tx and returns
+// their current position, or nil before their first fix. It locks the users
+// row, which unlike the user_radar row exists before the first fix; the
+// NO KEY UPDATE lock leaves foreign key checks on it unblocked.
+func lockLocation(ctx context.Context, tx *sql.Tx, userID int) (*models.UserRadar, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE", userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := scanRadar(tx.QueryRowContext(ctx, "SELECT "+radarColumns+" FROM user_radar WHERE user_id = $1", userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return current, err
+}
+
+func (r *RadarRepository) GetLocation(ctx context.Context, userID int) (*models.UserRadar, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	radar, err := scanRadar(r.db.DB.QueryRowContext(ctx, "SELECT "+radarColumns+" FROM user_radar WHERE user_id = $1", userID))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return radar, nil
+}
+
+// maskedPosition is the SQL twin of the position privacy.Mask reports, or
+// measures from for distance-only users, for a user_radar row ur and its
+// privacy settings p
+var maskedPosition = fmt.Sprintf(`CASE COALESCE(p.visibility, 'exact')
+		WHEN 'fuzzed' THEN radar_fuzz(ur.location::geometry, COALESCE(NULLIF(p.fuzz_meters, 0), %[1]d))
+		WHEN 'distance_only' THEN radar_fuzz(ur.location::geometry, %[1]d)
+		ELSE ur.location::geometry
+	END`, privacy.DefaultFuzzMeters)
+
+// maskSlack is how far, in meters, masking can move the position of a
+// user_radar row ur with privacy settings p: snapping to a grid moves it by
+// less than one cell
+var maskSlack = fmt.Sprintf(`CASE p.visibility
+		WHEN 'fuzzed' THEN COALESCE(NULLIF(p.fuzz_meters, 0), %[1]d)
+		ELSE %[1]d
+	END`, privacy.DefaultFuzzMeters)
+
+// FindNearby returns active users around the point, closest first. The
+// radius, area, ordering and paging apply to what privacy.Mask will report,
+// so narrowing a search cannot find an exact position that masking hides:
+// distances are those to the masked position, measured on the sphere like
+// geo.DistanceMeters, and areas match masked positions with the same planar
+// test as /clusters, leaving out distance-only users. Exact and masked users
+// are selected separately so the index conditions on exact users use the
+// radius or area as given, and only masked users are widened by how far
+// their own grid can move them. Nearest searches first take the users
+// closest to the point with the <-> KNN operator: a page needs no one
+// reported farther away than those, so that distance serves as the radius.
+// Nearest searches without a limit sort every active user.
+func (r *RadarRepository) FindNearby(ctx context.Context, filter repository.NearbyFilter) ([]models.NearbyUser, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	args := []interface{}{filter.Longitude, filter.Latitude, r.presenceTTL.Seconds(), privacy.DistanceStepKm}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	const origin = "ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography"
+
+	selectVisible := func(conditions string) string {
+		return `
+			SELECT
+				ur.user_id,
+				u.display_name,
+				u.avatar_url,
+				u.bio,
+				ST_Y(ur.location::geometry) as latitude,
+				ST_X(ur.location::geometry) as longitude,
+				ST_Distance(ur.location, ` + origin + `) / 1000 as distance_km,
+				COALESCE(p.visibility, 'exact') as visibility,
+				COALESCE(p.fuzz_meters, 0) as fuzz_meters,
+				ur.updated_at,
+				` + maskedPosition + ` as position
+			FROM user_radar ur
+			JOIN users u ON ur.user_id = u.id
+			LEFT JOIN user_radar_privacy p ON p.user_id = ur.user_id
+			WHERE ur.is_active = true
+			AND ur.updated_at >= CURRENT_TIMESTAMP - make_interval(secs => $3)` + conditions
+	}
+
+	var conditions string
+	if filter.ViewerID != 0 {
+		conditions += visibleTo(arg(filter.ViewerID))
+	}
+	if filter.MaxAccuracyMeters > 0 {
+		conditions += " AND ur.accuracy_meters <= " + arg(filter.MaxAccuracyMeters)
+	}
+	exact := conditions + " AND COALESCE(p.visibility, 'exact') = 'exact'"
+	masked := conditions + " AND p.visibility IN ('fuzzed', 'distance_only')"
+
+	const reportedKm = `CASE
+		WHEN visibility = 'distance_only'
+		THEN greatest(1, ceil(ST_Distance(position::geography, ` + origin + `, false) / 1000 / $4)) * $4
+		ELSE ST_Distance(position::geography, ` + origin + `, false) / 1000
+	END`
+
+	var radius, nearest string
+	if filter.RadiusKm > 0 {
+		radius = arg(filter.RadiusKm) + "::float8"
+	}
+	if filter.Nearest && filter.Limit > 0 && filter.Area == nil {
+		// At least k users are reported within the k-th smallest distance
+		// among the candidates, so no one farther away can make the page
+		k := arg(filter.Limit + filter.Offset)
+		nearest = `nearest AS (
+			SELECT ` + reportedKm + ` as reported_km
+			FROM ((` + selectVisible(exact) + `
+				ORDER BY ur.location <-> ` + origin + ` LIMIT ` + k + `
+			) UNION ALL (` + selectVisible(masked) + `
+				ORDER BY ur.location <-> ` + origin + ` LIMIT ` + k + `
+			)) candidates
+			ORDER BY reported_km
+			LIMIT ` + k + `
+		), `
+		bound := "(SELECT max(reported_km) FROM nearest)"
+		if radius != "" {
+			radius = "least(" + radius + ", " + bound + ")"
+		} else {
+			radius = bound
+		}
+	}
+
+	var outer []string
+	if radius != "" {
+		exact += " AND ST_DWithin(ur.location, " + origin + ", " + radius + " * 1000, false)"
+		masked += " AND ST_DWithin(ur.location, " + origin + ", " + radius + " * 1000 + " + maskSlack + ", false)"
+		outer = append(outer, "reported_km <= "+radius)
+	}
+	if filter.Area != nil {
+		encoded, err := polygonGeoJSON(filter.Area)
+		if err != nil {
+			return nil, err
+		}
+		area := "ST_SetSRID(ST_GeomFromGeoJSON(" + arg(encoded) + "), 4326)"
+		// MaxShiftDegrees bounds the largest grid; smaller ones move
+		// positions proportionally less
+		min, max := filter.Area.Bounds()
+		dLat, dLon := privacy.MaxShiftDegrees(math.Max(math.Abs(min.Latitude), math.Abs(max.Latitude)))
+		scale := fmt.Sprintf("(%s)::float8 / %d", maskSlack, privacy.MaxFuzzMeters)
+		exact += " AND ur.location::geometry && " + area
+		masked += " AND p.visibility = 'fuzzed' AND ur.location::geometry && ST_Expand(" + area + ", " +
+			arg(dLon) + " * " + scale + ", " + arg(dLat) + " * " + scale + ")"
+		outer = append(outer, "visibility <> 'distance_only'", "ST_Covers("+area+", position)")
+	}
+
+	query := `
+		WITH ` + nearest + `visible AS (` + selectVisible(exact) + `
+			UNION ALL` + selectVisible(masked) + `
+		), reported AS (
+			SELECT *, ` + reportedKm + ` as reported_km
+			FROM visible
+		)
+		SELECT user_id, display_name, avatar_url, bio, latitude, longitude, distance_km, visibility, fuzz_meters, updated_at
+		FROM reported
+	`
+	if len(outer) > 0 {
+		query += " WHERE " + strings.Join(outer, " AND ")
+	}
+	query += " ORDER BY reported_km, user_id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + arg(filter.Offset)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nearbyUsers := []models.NearbyUser{}
+	for rows.Next() {
+		var user models.NearbyUser
+		var latitude, longitude float64
+		if err := rows.Scan(&user.UserID, &user.Profile.DisplayName, &user.Profile.AvatarURL, &user.Profile.Bio, &latitude, &longitude, &user.DistanceKm, &user.Visibility, &user.FuzzMeters, &user.LastUpdateAt); err != nil {
+			return nil, err
+		}
+		user.Latitude = &latitude
+		user.Longitude = &longitude
+		nearbyUsers = append(nearbyUsers, user)
+	}
+
+	return nearbyUsers, rows.Err()
+}
+
+// Clusters buckets positions into grid cells with floor division so cells
+// line up across queries. Fuzzed positions are computed by radar_fuzz, the
+// SQL twin of privacy.Fuzz, before both the bucketing and the box filter; the
+// index only narrows candidates by a box widened by how far masking can move
+// a position.
+func (r *RadarRepository) Clusters(ctx context.Context, filter repository.ClusterFilter) ([]models.RadarCluster, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	dLat, dLon := privacy.MaxShiftDegrees(math.Max(math.Abs(filter.Min.Latitude), math.Abs(filter.Max.Latitude)))
+	args := []interface{}{
+		filter.Min.Longitude, filter.Min.Latitude, filter.Max.Longitude, filter.Max.Latitude,
+		filter.CellDegrees, r.presenceTTL.Seconds(), dLon, dLat,
+	}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `
+		WITH visible AS (
+			SELECT ` + maskedPosition + ` AS position
+			FROM user_radar ur
+			JOIN users u ON ur.user_id = u.id
+			LEFT JOIN user_radar_privacy p ON p.user_id = ur.user_id
+			WHERE ur.is_active = true
+			AND ur.updated_at >= CURRENT_TIMESTAMP - make_interval(secs => $6)
+			AND COALESCE(p.visibility, 'exact') IN ('exact', 'fuzzed')
+			AND ur.location::geometry && ST_Expand(ST_MakeEnvelope($1, $2, $3, $4, 4326), $7, $8)
+	`
+	if filter.ViewerID != 0 {
+		query += visibleTo(arg(filter.ViewerID))
+	}
+	query += `
+		)
+		SELECT
+			count(*),
+			ST_Y(ST_Centroid(ST_Collect(position))),
+			ST_X(ST_Centroid(ST_Collect(position))),
+			ST_YMin(ST_Extent(position)),
+			ST_XMin(ST_Extent(position)),
+			ST_YMax(ST_Extent(position)),
+			ST_XMax(ST_Extent(position))
+		FROM visible
+		WHERE position && ST_MakeEnvelope($1, $2, $3, $4, 4326)
+		GROUP BY floor(ST_X(position) / $5), floor(ST_Y(position) / $5)
+		ORDER BY 1 DESC, 2, 3
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clusters := []models.RadarCluster{}
+	for rows.Next() {
+		var cluster models.RadarCluster
+		if err := rows.Scan(&cluster.Count, &cluster.Latitude, &cluster.Longitude,
+			&cluster.Bounds.MinLatitude, &cluster.Bounds.MinLongitude, &cluster.Bounds.MaxLatitude, &cluster.Bounds.MaxLongitude); err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, rows.Err()
+}
+
+func (r *RadarRepository) CountActive(ctx context.Context) (int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(*) FROM user_radar
+		WHERE is_active = true
+		AND updated_at >= CURRENT_TIMESTAMP - make_interval(secs => $1)
+	`
+
+	var count int
+	err := r.db.DB.QueryRowContext(ctx, query, r.presenceTTL.Seconds()).Scan(&count)
+	return count, err
+}
+
+// ExpireStale marks active positions older than the presence TTL inactive.
+// updated_at is left alone so it still records when the user was last seen.
+func (r *RadarRepository) ExpireStale(ctx context.Context) ([]models.UserRadar, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE user_radar
+		SET is_active = false
+		WHERE is_active = true
+		AND updated_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		RETURNING ` + radarColumns
+
+	rows, err := r.db.DB.QueryContext(ctx, query, r.presenceTTL.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expired := []models.UserRadar{}
+	for rows.Next() {
+		radar, err := scanRadar(rows)
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, *radar)
+	}
+
+	return expired, rows.Err()
+}
+
+// GetPrivacy returns the user's privacy settings, or exact visibility when the
+// user never saved any
+func (r *RadarRepository) GetPrivacy(ctx context.Context, userID int) (*models.PrivacySettings, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT visibility, fuzz_meters, updated_at
+		FROM user_radar_privacy
+		WHERE user_id = $1
+	`
+
+	var settings models.PrivacySettings
+	err := r.db.DB.QueryRowContext(ctx, query, userID).
+		Scan(&settings.Visibility, &settings.FuzzMeters, &settings.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return &models.PrivacySettings{Visibility: models.VisibilityExact}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (r *RadarRepository) UpdatePrivacy(ctx context.Context, userID int, settings models.PrivacySettings) (*models.PrivacySettings, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_radar_privacy (user_id, visibility, fuzz_meters, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id)
+		DO UPDATE SET
+			visibility = $2,
+			fuzz_meters = $3,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING visibility, fuzz_meters, updated_at
+	`
+
+	var updated models.PrivacySettings
+	err := r.db.DB.QueryRowContext(ctx, query, userID, settings.Visibility, settings.FuzzMeters).
+		Scan(&updated.Visibility, &updated.FuzzMeters, &updated.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (r *RadarRepository) History(ctx context.Context, userID int, filter repository.HistoryFilter) ([]models.LocationPoint, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	// Select the newest points first so LIMIT keeps the most recent part of
+	// the trail, then reverse into chronological order below
+	query := `
+		SELECT
+			ST_Y(location::geometry) as latitude,
+			ST_X(location::geometry) as longitude,
+			is_active,
+			accuracy_meters,
+			altitude_meters,
+			speed_mps,
+			course_degrees,
+			device_timestamp,
+			recorded_at
+		FROM location_history
+		WHERE user_id = $1
+		AND ($2::timestamp IS NULL OR recorded_at >= $2)
+		AND ($3::timestamp IS NULL OR recorded_at < $3)
+		ORDER BY recorded_at DESC, id DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, utcOrNil(filter.From), utcOrNil(filter.To), filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []models.LocationPoint{}
+	for rows.Next() {
+		var point models.LocationPoint
+		if err := rows.Scan(&point.Latitude, &point.Longitude, &point.IsActive,
+			&point.AccuracyMeters, &point.AltitudeMeters, &point.SpeedMps, &point.CourseDegrees, &point.DeviceTimestamp, &point.RecordedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+
+	return points, nil
+}
+
+// utcOrNil converts an optional time into a query argument, passing NULL for
+// nil so the SQL can skip the bound
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func scanRadar(row rowScanner) (*models.UserRadar, error) {
+	var radar models.UserRadar
+	err := row.Scan(&radar.ID, &radar.UserID, &radar.Latitude, &radar.Longitude, &radar.IsActive,
+		&radar.AccuracyMeters, &radar.AltitudeMeters, &radar.SpeedMps, &radar.CourseDegrees, &radar.DeviceTimestamp,
+		&radar.CreatedAt, &radar.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &radar, nil
+}
+
+// visibleTo returns the condition that leaves the viewer and users blocked in
+// either direction out of a query on user_radar ur
+func visibleTo(viewer string) string {
+	return " AND ur.user_id <> " + viewer + `
+		AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = ` + viewer + ` AND b.blocked_id = ur.user_id)
+			OR (b.blocker_id = ur.user_id AND b.blocked_id = ` + viewer + `)
+		)`
+}
+
+// polygonGeoJSON encodes a polygon as a GeoJSON geometry for ST_GeomFromGeoJSON
+func polygonGeoJSON(polygon geo.Polygon) (string, error) {
+	coordinates := make([][][]float64, 0, len(polygon))
+	for _, ring := range polygon {
+		positions := make([][]float64, 0, len(ring))
+		for _, point := range ring {
+			positions = append(positions, []float64{point.Longitude, point.Latitude})
+		}
+		coordinates = append(coordinates, positions)
+	}
+
+	encoded, err := json.Marshal(models.GeoJSONPolygon{Type: "Polygon", Coordinates: coordinates})
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
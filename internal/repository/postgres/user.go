@@ -0,0 +1,325 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"api-backend/internal/database"
+	"api-backend/internal/models"
+	"api-backend/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+// Postgres SQLSTATEs for unique and foreign key constraint failures
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// userColumns are the columns scanUser reads, in order
+const userColumns = "id, email, display_name, avatar_url, bio, created_at, updated_at"
+
+type UserRepository struct {
+	db *database.Database
+}
+
+func NewUserRepository(db *database.Database) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) Create(ctx context.Context, email, passwordHash string) (*models.User, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(r.db.DB.QueryRowContext(ctx,
+		"INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING "+userColumns,
+		email,
+		passwordHash,
+	))
+
+	if isUniqueViolation(err) {
+		return nil, repository.ErrDuplicateEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(r.db.DB.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE id = $1",
+		id,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+	var passwordHash sql.NullString
+	err := r.db.DB.QueryRowContext(ctx,
+		"SELECT id, email, display_name, avatar_url, bio, password_hash, created_at, updated_at FROM users WHERE email = $1",
+		email,
+	).Scan(&user.ID, &user.Email, &user.Profile.DisplayName, &user.Profile.AvatarURL, &user.Profile.Bio, &passwordHash, &user.CreatedAt, &user.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.PasswordHash = passwordHash.String
+	return &user, nil
+}
+
+// List returns users matching filter using keyset pagination on
+// (created_at, id), which stays fast regardless of page depth
+func (r *UserRepository) List(ctx context.Context, filter repository.UserFilter) ([]models.User, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.EmailContains != "" {
+		conditions = append(conditions, "email ILIKE '%' || "+arg(escapeLike(filter.EmailContains))+" || '%'")
+	}
+	if filter.EmailPrefix != "" {
+		conditions = append(conditions, "email ILIKE "+arg(escapeLike(filter.EmailPrefix))+" || '%'")
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+arg(filter.CreatedAfter.UTC()))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+arg(filter.CreatedBefore.UTC()))
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s (%s, %s)",
+			comparison, arg(filter.After.CreatedAt.UTC()), arg(filter.After.ID)))
+	}
+
+	query := "SELECT " + userColumns + " FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT %s", direction, direction, arg(filter.Limit))
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *UserRepository) Update(ctx context.Context, id int, update repository.UserUpdate, expectedUpdatedAt *time.Time) (*models.User, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	args := []interface{}{id}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	assignments := []string{"updated_at = CURRENT_TIMESTAMP"}
+	if update.Email != nil {
+		assignments = append(assignments, "email = "+arg(*update.Email))
+	}
+	if update.Profile != nil {
+		assignments = append(assignments,
+			"display_name = "+arg(update.Profile.DisplayName),
+			"avatar_url = "+arg(update.Profile.AvatarURL),
+			"bio = "+arg(update.Profile.Bio),
+		)
+	}
+
+	query := "UPDATE users SET " + strings.Join(assignments, ", ") + " WHERE id = $1"
+	if expectedUpdatedAt != nil {
+		query += " AND updated_at = " + arg(expectedUpdatedAt.UTC())
+	}
+	query += " RETURNING " + userColumns
+
+	user, err := scanUser(r.db.DB.QueryRowContext(ctx, query, args...))
+
+	if isUniqueViolation(err) {
+		return nil, repository.ErrDuplicateEmail
+	}
+
+	// No row means the user is gone or the version guard did not match
+	if err == sql.ErrNoRows {
+		exists, existsErr := r.Exists(ctx, id)
+		if existsErr != nil {
+			return nil, existsErr
+		}
+		if exists {
+			return nil, repository.ErrVersionConflict
+		}
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) Exists(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := r.db.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", id).Scan(&exists)
+	return exists, err
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.DB.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) Block(ctx context.Context, blockerID, blockedID int) error {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.DB.ExecContext(ctx,
+		"INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		blockerID,
+		blockedID,
+	)
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *UserRepository) Unblock(ctx context.Context, blockerID, blockedID int) error {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.DB.ExecContext(ctx,
+		"DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2",
+		blockerID,
+		blockedID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) BlockedUserIDs(ctx context.Context, userID int) ([]int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT blocked_id FROM user_blocks WHERE blocker_id = $1
+		UNION
+		SELECT blocker_id FROM user_blocks WHERE blocked_id = $1
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Email, &user.Profile.DisplayName, &user.Profile.AvatarURL, &user.Profile.Bio, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
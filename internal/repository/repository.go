@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+)
+
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrDuplicateEmail = errors.New("email already exists")
+	// ErrVersionConflict means the row changed since the caller last read it
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// UserRepository persists user accounts
+type UserRepository interface {
+	Create(ctx context.Context, email, passwordHash string) (*models.User, error)
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	List(ctx context.Context, filter UserFilter) ([]models.User, error)
+	// Update applies the non-nil fields of update and bumps updated_at. When
+	// expectedUpdatedAt is set the write only succeeds if the stored
+	// updated_at still matches it, otherwise ErrVersionConflict is returned.
+	Update(ctx context.Context, id int, update UserUpdate, expectedUpdatedAt *time.Time) (*models.User, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	Delete(ctx context.Context, id int) error
+	// Block records that blockerID blocked blockedID; blocking twice is not
+	// an error. It returns ErrNotFound when either user does not exist.
+	Block(ctx context.Context, blockerID, blockedID int) error
+	// Unblock removes a block, returning ErrNotFound if there was none
+	Unblock(ctx context.Context, blockerID, blockedID int) error
+	// BlockedUserIDs returns every user who blocked userID or was blocked
+	// by them, since blocks hide both sides from each other
+	BlockedUserIDs(ctx context.Context, userID int) ([]int, error)
+}
+
+// LocationCheck is called by UpsertLocation with the user's current position,
+// nil before their first fix, while other location writes for the user wait,
+// so it sees the position the write replaces. An error aborts the write and
+// is returned. It must not call the radar repository.
+type LocationCheck func(current *models.UserRadar) error
+
+// LocationFilter is called by AppendLocations like LocationCheck and returns
+// the fixes to store
+type LocationFilter func(current *models.UserRadar, fixes []models.LocationPoint) []models.LocationPoint
+
+// UserUpdate lists the user fields to change; nil fields are left as is
+type UserUpdate struct {
+	Email   *string
+	Profile *models.Profile
+}
+
+// UserFilter selects a page of users ordered by (created_at, id)
+type UserFilter struct {
+	EmailContains string
+	EmailPrefix   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Ascending     bool
+	// After is the keyset position of the last row on the previous page
+	After *UserCursor
+	Limit int
+}
+
+// UserCursor is a position in the (created_at, id) ordering of users
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// RadarRepository persists user locations and their history and answers
+// proximity queries
+type RadarRepository interface {
+	// UpsertLocation replaces the user's live position and appends it to the
+	// history, stamped with the server clock; fix.RecordedAt is ignored. A
+	// non-nil check runs first, against the position being replaced.
+	UpsertLocation(ctx context.Context, userID int, fix models.LocationPoint, check LocationCheck) (*models.UserRadar, error)
+	// AppendLocations stores a batch of timestamped fixes in one transaction.
+	// A non-nil filter picks the fixes to consider, against the position
+	// before the batch. Fixes not newer than the current position are
+	// ignored; the others are added to the history and the newest becomes the
+	// current position. It returns the current position afterwards and how
+	// many fixes were stored.
+	AppendLocations(ctx context.Context, userID int, fixes []models.LocationPoint, filter LocationFilter) (*models.UserRadar, int, error)
+	// GetLocation returns the user's current radar row or ErrNotFound
+	GetLocation(ctx context.Context, userID int) (*models.UserRadar, error)
+	// FindNearby returns active, non-hidden users whose position is not
+	// stale, closest first. The radius and ordering apply to the distance
+	// privacy.Mask reports, but results carry exact positions plus the
+	// user's privacy settings; callers mask them before responding.
+	FindNearby(ctx context.Context, filter NearbyFilter) ([]models.NearbyUser, error)
+	// Clusters counts the users inside the filter's box per grid cell. Unlike
+	// FindNearby it applies privacy itself: fuzzed users count at their
+	// fuzzed position, and distance-only and hidden users are left out.
+	Clusters(ctx context.Context, filter ClusterFilter) ([]models.RadarCluster, error)
+	// GetPrivacy returns the user's privacy settings, defaulting to exact
+	// visibility when none were saved
+	GetPrivacy(ctx context.Context, userID int) (*models.PrivacySettings, error)
+	UpdatePrivacy(ctx context.Context, userID int, settings models.PrivacySettings) (*models.PrivacySettings, error)
+	// CountActive returns how many users have a current, non-stale position,
+	// including users hidden from the radar
+	CountActive(ctx context.Context) (int, error)
+	// ExpireStale deactivates positions older than the presence TTL and
+	// returns the rows it changed
+	ExpireStale(ctx context.Context) ([]models.UserRadar, error)
+	// History returns the most recent points of a user's trail within the
+	// filter's time range, in chronological order
+	History(ctx context.Context, userID int, filter HistoryFilter) ([]models.LocationPoint, error)
+}
+
+// NearbyFilter selects users around a point, which is also where distances
+// are measured from. A zero RadiusKm does not bound the search, a non-nil
+// Area restricts it to users whose masked position is inside a polygon with
+// planar edges, which leaves out distance-only users, and a zero Limit returns
+// every match. Nearest only plans the search differently: the users closest
+// to the point bound it, so k-nearest searches need no radius to use an
+// index. A positive MaxAccuracyMeters drops users whose last fix was less
+// accurate or reported no accuracy. When ViewerID is set the viewer and
+// users blocked in either direction are left out.
+type NearbyFilter struct {
+	Latitude          float64
+	Longitude         float64
+	RadiusKm          float64
+	Area              geo.Polygon
+	Nearest           bool
+	Limit             int
+	Offset            int
+	ViewerID          int
+	MaxAccuracyMeters float64
+}
+
+// ClusterFilter groups the active users between the Min and Max corners into
+// square cells of CellDegrees, aligned to multiples of CellDegrees. When
+// ViewerID is set the viewer and users blocked in either direction are left
+// out.
+type ClusterFilter struct {
+	Min         geo.Point
+	Max         geo.Point
+	CellDegrees float64
+	ViewerID    int
+}
+
+// HistoryFilter bounds a location history query; From is inclusive, To exclusive
+type HistoryFilter struct {
+	From  *time.Time
+	To    *time.Time
+	Limit int
+}
+
+// GeofenceRepository persists geofences and the enter/exit events recorded
+// when their owner crosses them
+type GeofenceRepository interface {
+	Create(ctx context.Context, fence models.Geofence) (*models.Geofence, error)
+	GetByID(ctx context.Context, id int) (*models.Geofence, error)
+	ListByOwner(ctx context.Context, ownerID int) ([]models.Geofence, error)
+	Update(ctx context.Context, fence models.Geofence) (*models.Geofence, error)
+	Delete(ctx context.Context, id int) error
+	// RecordTransitions compares the previous and current position of userID
+	// against the user's geofences and stores an event for every fence whose
+	// inside/outside state changed. previous is nil for a user's first fix.
+	RecordTransitions(ctx context.Context, userID int, previous *geo.Point, current geo.Point) ([]models.GeofenceEvent, error)
+	ListEvents(ctx context.Context, filter GeofenceEventFilter) ([]models.GeofenceEvent, error)
+}
+
+// GeofenceEventFilter selects the newest events for a user or a fence; zero
+// IDs are not filtered on
+type GeofenceEventFilter struct {
+	UserID     int
+	GeofenceID int
+	Limit      int
+}
+
+// LocationFlagRepository stores implausible location updates for moderation
+type LocationFlagRepository interface {
+	Create(ctx context.Context, flag models.LocationFlag) (*models.LocationFlag, error)
+	// ListByUser returns the user's newest flags first
+	ListByUser(ctx context.Context, userID int, limit int) ([]models.LocationFlag, error)
+}
@@ -0,0 +1,91 @@
+package spoofing
+
+import (
+	"math"
+	"time"
+
+	"api-backend/internal/geo"
+	"api-backend/internal/models"
+)
+
+// Modes decide what happens to a location update that moves implausibly fast
+const (
+	// ModeReject drops the update and records it
+	ModeReject = "reject"
+	// ModeFlag stores the update as usual and records it for review
+	ModeFlag = "flag"
+)
+
+// jitterMeters is movement always tolerated on top of the reported accuracy,
+// since GPS positions wander even when the device stands still
+const jitterMeters = 100
+
+// minElapsed keeps fixes taken at the same instant from implying an infinite
+// speed
+const minElapsed = time.Second
+
+// Checker compares consecutive fixes of a user against a maximum speed
+type Checker struct {
+	maxSpeedKmh float64
+	mode        string
+}
+
+// NewChecker returns a checker for the given speed limit and mode. A
+// maxSpeedKmh of zero or less disables the check.
+func NewChecker(maxSpeedKmh float64, mode string) *Checker {
+	return &Checker{maxSpeedKmh: maxSpeedKmh, mode: mode}
+}
+
+// Rejects reports whether implausible updates are dropped rather than stored
+func (c *Checker) Rejects() bool {
+	return c.mode == ModeReject
+}
+
+// Check returns a flag when getting from previous to next implies a speed
+// above the limit, or nil if the move is plausible. Distance within both
+// fixes' accuracy plus some jitter is not counted. The flag's UserID is left
+// for the caller to fill in.
+func (c *Checker) Check(previous, next models.LocationPoint) *models.LocationFlag {
+	if c.maxSpeedKmh <= 0 {
+		return nil
+	}
+
+	distance := geo.DistanceMeters(previous.Latitude, previous.Longitude, next.Latitude, next.Longitude)
+	counted := distance - jitterMeters - accuracy(previous) - accuracy(next)
+	if counted <= 0 {
+		return nil
+	}
+
+	elapsed := next.RecordedAt.Sub(previous.RecordedAt)
+	if elapsed < minElapsed {
+		elapsed = minElapsed
+	}
+
+	speedKmh := counted / 1000 / elapsed.Hours()
+	if speedKmh <= c.maxSpeedKmh {
+		return nil
+	}
+
+	action := models.FlagActionFlagged
+	if c.Rejects() {
+		action = models.FlagActionRejected
+	}
+
+	return &models.LocationFlag{
+		FromLatitude:   previous.Latitude,
+		FromLongitude:  previous.Longitude,
+		ToLatitude:     next.Latitude,
+		ToLongitude:    next.Longitude,
+		DistanceMeters: distance,
+		ElapsedSeconds: next.RecordedAt.Sub(previous.RecordedAt).Seconds(),
+		SpeedKmh:       math.Round(speedKmh),
+		Action:         action,
+	}
+}
+
+func accuracy(point models.LocationPoint) float64 {
+	if point.AccuracyMeters == nil {
+		return 0
+	}
+	return *point.AccuracyMeters
+}
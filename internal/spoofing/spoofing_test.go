@@ -0,0 +1,69 @@
+package spoofing
+
+import (
+	"testing"
+	"time"
+
+	"api-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func fixAt(latitude, longitude float64, after time.Duration) models.LocationPoint {
+	return models.LocationPoint{Latitude: latitude, Longitude: longitude, RecordedAt: start.Add(after)}
+}
+
+func TestCheck_PlausibleMoves(t *testing.T) {
+	checker := NewChecker(900, ModeReject)
+	berlin := fixAt(52.5200, 13.4050, 0)
+
+	// About 1.3 km in five minutes is a bike ride
+	assert.Nil(t, checker.Check(berlin, fixAt(52.5300, 13.4150, 5*time.Minute)))
+
+	// Berlin to Munich in an hour is a flight
+	assert.Nil(t, checker.Check(berlin, fixAt(48.1351, 11.5820, time.Hour)))
+
+	// GPS jitter at the same instant is tolerated
+	assert.Nil(t, checker.Check(berlin, fixAt(52.5205, 13.4050, 0)))
+}
+
+func TestCheck_Teleport(t *testing.T) {
+	checker := NewChecker(900, ModeReject)
+
+	flag := checker.Check(fixAt(52.5200, 13.4050, 0), fixAt(35.6762, 139.6503, time.Second))
+	assert.NotNil(t, flag)
+	assert.Equal(t, models.FlagActionRejected, flag.Action)
+	assert.InDelta(t, 8900000, flag.DistanceMeters, 100000)
+	assert.Equal(t, 1.0, flag.ElapsedSeconds)
+	assert.Greater(t, flag.SpeedKmh, 900.0)
+	assert.Equal(t, 35.6762, flag.ToLatitude)
+
+	// Fixes taken at the same instant are treated as a second apart
+	flag = checker.Check(fixAt(52.5200, 13.4050, 0), fixAt(52.6200, 13.4050, 0))
+	assert.NotNil(t, flag)
+	assert.Zero(t, flag.ElapsedSeconds)
+}
+
+func TestCheck_AccuracyIsNotCounted(t *testing.T) {
+	checker := NewChecker(100, ModeReject)
+	accuracy := 2000.0
+
+	previous := fixAt(52.5200, 13.4050, 0)
+	next := fixAt(52.5300, 13.4050, 10*time.Second) // about 1.1 km
+	assert.NotNil(t, checker.Check(previous, next))
+
+	next.AccuracyMeters = &accuracy
+	assert.Nil(t, checker.Check(previous, next))
+}
+
+func TestCheck_ModesAndDisabled(t *testing.T) {
+	previous, next := fixAt(52.5200, 13.4050, 0), fixAt(35.6762, 139.6503, time.Minute)
+
+	flag := NewChecker(900, ModeFlag).Check(previous, next)
+	assert.NotNil(t, flag)
+	assert.Equal(t, models.FlagActionFlagged, flag.Action)
+
+	assert.Nil(t, NewChecker(0, ModeReject).Check(previous, next))
+}
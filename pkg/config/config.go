@@ -2,23 +2,137 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port           string
-	DatabaseURL    string
-	Environment    string
-	AllowedOrigins []string
+	Port            string
+	DatabaseURL     string
+	Environment     string
+	AllowedOrigins  []string
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// AdminUserIDs are the users who may read every account
+	AdminUserIDs []int
+	// Server timeouts; ShutdownTimeout bounds draining requests on SIGTERM
+	// and should stay below the platform's grace period. ShutdownDelay is
+	// how long readiness fails before draining starts.
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	ShutdownDelay   time.Duration
+	// QueryTimeout bounds each repository call and is the Postgres
+	// statement_timeout; 0 disables both
+	QueryTimeout time.Duration
+	// PresenceTTL is how long a radar position counts as current without an
+	// update; JanitorInterval is how often stale positions are deactivated
+	PresenceTTL     time.Duration
+	JanitorInterval time.Duration
+	// MaxSpeedKmh is the fastest plausible move between two fixes, 0 turns
+	// the check off; SpoofMode is "reject" or "flag"
+	MaxSpeedKmh float64
+	SpoofMode   string
+	// RateLimitStore is "memory" to limit each instance on its own or
+	// "postgres" to share limits between instances
+	RateLimitStore string
+	// TrustedProxies are the proxy addresses or CIDRs allowed to set the
+	// client IP through X-Forwarded-For; empty trusts none, so the client IP
+	// is always the connection's remote address. It is mandatory in
+	// production.
+	TrustedProxies []string
+	// LogLevel is the least severe level that is logged; GoogleCloudProject,
+	// when set, links request logs to their Cloud Trace traces
+	LogLevel           slog.Level
+	GoogleCloudProject string
+	// MetricsToken, when set, is the bearer token required on /metrics; it
+	// is mandatory in production
+	MetricsToken string
+	// TracesEndpoint is the OTLP/HTTP URL spans are exported to, tracing is
+	// off when empty; TraceSampleRatio is the share of new traces recorded
+	TracesEndpoint   string
+	ServiceName      string
+	TraceSampleRatio float64
 }
 
 func Load() (*Config, error) {
-	if os.Getenv("ENVIRONMENT") != "production" {
-		if err := godotenv.Load(); err != nil {
-			fmt.Println("No .env file found, using environment variables")
-		}
+	loadDotEnv()
+
+	accessTokenTTL, err := getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenTTL, err := getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	readTimeout, err := getEnvDuration("HTTP_READ_TIMEOUT", 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	writeTimeout, err := getEnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	idleTimeout, err := getEnvDuration("HTTP_IDLE_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	shutdownTimeout, err := getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	shutdownDelay, err := getEnvDuration("SHUTDOWN_DELAY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	queryTimeout, err := getEnvDuration("DB_QUERY_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	presenceTTL, err := getEnvDuration("RADAR_PRESENCE_TTL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	janitorInterval, err := getEnvDuration("RADAR_JANITOR_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSpeedKmh, err := getEnvFloat("RADAR_MAX_SPEED_KMH", 1200)
+	if err != nil {
+		return nil, err
+	}
+
+	traceSampleRatio, err := getEnvFloat("TRACE_SAMPLE_RATIO", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	adminUserIDs, err := getEnvIntList("ADMIN_USER_IDS")
+	if err != nil {
+		return nil, err
+	}
+
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(getEnv("LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
 	}
 
 	config := &Config{
@@ -28,18 +142,157 @@ func Load() (*Config, error) {
 		AllowedOrigins: []string{
 			getEnv("ALLOWED_ORIGIN", "http://localhost:3000"),
 		},
+		JWTSecret:          getEnv("JWT_SECRET", ""),
+		AccessTokenTTL:     accessTokenTTL,
+		RefreshTokenTTL:    refreshTokenTTL,
+		AdminUserIDs:       adminUserIDs,
+		ReadTimeout:        readTimeout,
+		WriteTimeout:       writeTimeout,
+		IdleTimeout:        idleTimeout,
+		ShutdownTimeout:    shutdownTimeout,
+		ShutdownDelay:      shutdownDelay,
+		QueryTimeout:       queryTimeout,
+		PresenceTTL:        presenceTTL,
+		JanitorInterval:    janitorInterval,
+		MaxSpeedKmh:        maxSpeedKmh,
+		SpoofMode:          getEnv("RADAR_SPOOF_MODE", "reject"),
+		RateLimitStore:     getEnv("RATE_LIMIT_STORE", "memory"),
+		TrustedProxies:     getEnvList("TRUSTED_PROXIES"),
+		LogLevel:           logLevel,
+		GoogleCloudProject: getEnv("GOOGLE_CLOUD_PROJECT", ""),
+		MetricsToken:       getEnv("METRICS_TOKEN", ""),
+		TracesEndpoint:     getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", ""),
+		ServiceName:        getEnv("OTEL_SERVICE_NAME", "api-backend"),
+		TraceSampleRatio:   traceSampleRatio,
 	}
 
 	if config.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL is required")
 	}
 
+	if config.JWTSecret == "" {
+		return nil, fmt.Errorf("JWT_SECRET is required")
+	}
+
+	if config.ReadTimeout <= 0 || config.WriteTimeout <= 0 || config.IdleTimeout <= 0 || config.ShutdownTimeout <= 0 {
+		return nil, fmt.Errorf("HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT, HTTP_IDLE_TIMEOUT and SHUTDOWN_TIMEOUT must be positive")
+	}
+
+	if config.ShutdownDelay < 0 {
+		return nil, fmt.Errorf("SHUTDOWN_DELAY must not be negative")
+	}
+
+	if config.QueryTimeout < 0 {
+		return nil, fmt.Errorf("DB_QUERY_TIMEOUT must not be negative")
+	}
+
+	if config.PresenceTTL <= 0 || config.JanitorInterval <= 0 {
+		return nil, fmt.Errorf("RADAR_PRESENCE_TTL and RADAR_JANITOR_INTERVAL must be positive")
+	}
+
+	if config.MaxSpeedKmh < 0 {
+		return nil, fmt.Errorf("RADAR_MAX_SPEED_KMH must not be negative")
+	}
+
+	if config.SpoofMode != "reject" && config.SpoofMode != "flag" {
+		return nil, fmt.Errorf("RADAR_SPOOF_MODE must be reject or flag")
+	}
+
+	if config.TraceSampleRatio < 0 || config.TraceSampleRatio > 1 {
+		return nil, fmt.Errorf("TRACE_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if config.RateLimitStore != "memory" && config.RateLimitStore != "postgres" {
+		return nil, fmt.Errorf("RATE_LIMIT_STORE must be memory or postgres")
+	}
+
+	if config.Environment == "production" && config.MetricsToken == "" {
+		return nil, fmt.Errorf("METRICS_TOKEN is required in production")
+	}
+
+	// Without trusted proxies every client behind the platform's proxy shares
+	// its address, and so one rate limit bucket
+	if config.Environment == "production" && len(config.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("TRUSTED_PROXIES is required in production")
+	}
+
 	return config, nil
 }
 
+// LoadDatabaseURL reads only DATABASE_URL, for tools such as the migrate
+// command that need none of the API's settings
+func LoadDatabaseURL() (string, error) {
+	loadDotEnv()
+
+	databaseURL := getEnv("DATABASE_URL", "")
+	if databaseURL == "" {
+		return "", fmt.Errorf("DATABASE_URL is required")
+	}
+	return databaseURL, nil
+}
+
+// loadDotEnv reads a .env file into the environment outside production
+func loadDotEnv() {
+	if os.Getenv("ENVIRONMENT") != "production" {
+		if err := godotenv.Load(); err != nil {
+			fmt.Println("No .env file found, using environment variables")
+		}
+	}
+}
+
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return fallback
 }
+
+// getEnvList splits a comma-separated variable, skipping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvIntList parses a comma-separated list of integers
+func getEnvIntList(key string) ([]int, error) {
+	var values []int
+	for _, value := range getEnvList(key) {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		values = append(values, i)
+	}
+	return values, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+func getEnvFloat(key string, fallback float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return f, nil
+}
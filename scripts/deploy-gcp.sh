@@ -5,6 +5,12 @@ set -e
 PROJECT_ID=${GCP_PROJECT_ID:-"your-project-id"}
 REGION=${GCP_REGION:-"us-central1"}
 SERVICE_NAME="api-backend"
+# Secret Manager secrets holding the values the service refuses to start without
+JWT_SECRET_NAME=${JWT_SECRET_NAME:-"jwt-secret"}
+METRICS_TOKEN_SECRET_NAME=${METRICS_TOKEN_SECRET_NAME:-"metrics-token"}
+# Cloud Run's front end connects to the container from link-local addresses;
+# add 35.191.0.0/16,130.211.0.0/22 when serving through an external load balancer
+TRUSTED_PROXIES=${TRUSTED_PROXIES:-"169.254.0.0/16"}
 
 echo "Deploying to Google Cloud Platform..."
 echo "Project: $PROJECT_ID"
@@ -27,7 +33,8 @@ gcloud run deploy $SERVICE_NAME \
   --platform managed \
   --region $REGION \
   --allow-unauthenticated \
-  --set-env-vars ENVIRONMENT=production
+  --set-env-vars "^@^ENVIRONMENT=production@TRUSTED_PROXIES=$TRUSTED_PROXIES" \
+  --set-secrets JWT_SECRET=$JWT_SECRET_NAME:latest,METRICS_TOKEN=$METRICS_TOKEN_SECRET_NAME:latest
 
 echo "Deployment complete!"
 echo "Note: Don't forget to set up your DATABASE_URL environment variable in Cloud Run"